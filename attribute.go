@@ -0,0 +1,305 @@
+package gojsx
+
+import (
+	"fmt"
+	"github.com/dop251/goja"
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// reservedProps 不会被渲染为属性
+//
+// /node_modules/react-dom/cjs/react-dom-server-legacy.node.development.js RESERVED
+var reservedProps = map[string]bool{
+	"children":                       true,
+	"dangerouslySetInnerHTML":        true,
+	"defaultValue":                   true,
+	"defaultChecked":                 true,
+	"innerHTML":                      true,
+	"suppressContentEditableWarning": true,
+	"suppressHydrationWarning":       true,
+	"key":                            true,
+	"ref":                            true,
+}
+
+// isEventProp 与 react 的 shouldIgnoreAttribute 一致，如 onClick 不会被渲染
+func isEventProp(k string) bool {
+	return len(k) > 2 && (k[0] == 'o' || k[0] == 'O') && (k[1] == 'n' || k[1] == 'N')
+}
+
+// renderAttribute 参考 react-dom 的 shouldRemoveAttribute 渲染一个属性，
+// null、undefined 与函数不会被渲染，bool 只会在布尔属性以及 data-*、aria-* 中渲染。
+func renderAttribute(s io.StringWriter, k string, val interface{}) {
+	if reservedProps[k] || isEventProp(k) || isNullish(val) {
+		return
+	}
+	if o, ok := val.(*goja.Object); ok && o.ClassName() == "Function" {
+		return
+	}
+
+	name := k
+	if n, ok := propsToAttr[k]; ok {
+		name = n
+	}
+
+	b, isBool := val.(bool)
+	switch {
+	case boolAttr[name]:
+		// 与 react 一致，渲染为 disabled=""
+		if truthy(val) {
+			s.WriteString(" ")
+			s.WriteString(name)
+			s.WriteString(`=""`)
+		}
+		return
+	case overloadedBoolAttr[name]:
+		if isBool {
+			if b {
+				s.WriteString(" ")
+				s.WriteString(name)
+				s.WriteString(`=""`)
+			}
+			return
+		}
+	case booleanishAttr[name]:
+	case positiveNumericAttr[name]:
+		if n, ok := toNumber(val); !ok || n < 1 {
+			return
+		}
+	case numericAttr[name]:
+		if _, ok := toNumber(val); !ok {
+			return
+		}
+	default:
+		if isBool && !strings.HasPrefix(name, "data-") && !strings.HasPrefix(name, "aria-") {
+			return
+		}
+	}
+
+	vs, ok := attributeString(val)
+	if !ok {
+		return
+	}
+	s.WriteString(" ")
+	s.WriteString(name)
+	s.WriteString(`="`)
+	s.WriteString(vs)
+	s.WriteString(`"`)
+}
+
+// attributeString 返回转义后的属性值，不支持的类型（如对象）返回 false
+func attributeString(val interface{}) (string, bool) {
+	switch t := val.(type) {
+	case string:
+		return template.HTMLEscapeString(t), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case int, int64, int32, int16, int8, float64, float32, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf(`%v`, t), true
+	}
+	return "", false
+}
+
+// truthy 与 js 中的 Boolean(val) 一致
+func truthy(val interface{}) bool {
+	switch t := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case goja.Value:
+		return t.ToBoolean()
+	}
+	if n, ok := toNumber(val); ok {
+		return n != 0
+	}
+	return true
+}
+
+// toNumber 将数值或数字字符串转为 float64，NaN 返回 false
+func toNumber(val interface{}) (float64, bool) {
+	var n float64
+	switch t := val.(type) {
+	case int:
+		n = float64(t)
+	case int8:
+		n = float64(t)
+	case int16:
+		n = float64(t)
+	case int32:
+		n = float64(t)
+	case int64:
+		n = float64(t)
+	case uint:
+		n = float64(t)
+	case uint8:
+		n = float64(t)
+	case uint16:
+		n = float64(t)
+	case uint32:
+		n = float64(t)
+	case uint64:
+		n = float64(t)
+	case float32:
+		n = float64(t)
+	case float64:
+		n = t
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, false
+		}
+		n = f
+	default:
+		return 0, false
+	}
+	return n, !math.IsNaN(n)
+}
+
+// formState 记录渲染 <select> 的子节点时需要的状态
+type formState struct {
+	// selectValue 是 <select value> 的值，<option> 根据它计算 selected
+	selectValue interface{}
+	inSelect    bool
+}
+
+// formElementProps 参考 react-dom/server 处理表单元素的 value 与 checked：
+//   - <input>: value 优先于 defaultValue，checked 优先于 defaultChecked
+//   - <textarea>: value (或 defaultValue) 被渲染为内容
+//   - <select>: value (或 defaultValue) 不渲染为属性，用于计算 <option> 的 selected
+//   - <option>: 在 <select value> 中时，值相同则添加 selected
+func formElementProps(ctx *RenderCtx, n *vnode, attrs []attribute, children interface{}) ([]attribute, interface{}) {
+	switch n.nodeName {
+	case "input":
+		// 与 react-dom/server 一致，checked 与 value 在最后渲染
+		checked, hasChecked := controlledAttribute(attrs, "checked", "defaultChecked")
+		value, hasValue := controlledAttribute(attrs, "value", "defaultValue")
+		attrs = withoutAttributes(attrs, "checked", "defaultChecked", "value", "defaultValue")
+		if hasChecked {
+			attrs = append(attrs, attribute{key: "checked", value: checked})
+		}
+		if hasValue {
+			attrs = append(attrs, attribute{key: "value", value: value})
+		}
+	case "textarea":
+		v, ok := controlledAttribute(attrs, "value", "defaultValue")
+		attrs = withoutAttributes(attrs, "value", "defaultValue")
+		if ok {
+			children = v
+		}
+	case "select":
+		// 在 renderVNode 中使用 selectValue 渲染 children
+		attrs = withoutAttributes(attrs, "value", "defaultValue")
+	case "option":
+		if !ctx.form.inSelect || isNullish(ctx.form.selectValue) {
+			break
+		}
+		value, ok := n.attr("value")
+		if !ok || isNullish(value) {
+			value = textContent(children)
+		}
+		attrs = withoutAttributes(attrs, "selected")
+		if optionSelected(ctx.form.selectValue, value) {
+			attrs = append(attrs, attribute{key: "selected", value: true})
+		}
+	}
+	return attrs, children
+}
+
+// controlledAttribute 返回 key 的值，不存在时返回 defaultKey 的值，如 value 与 defaultValue
+func controlledAttribute(attrs []attribute, key, defaultKey string) (interface{}, bool) {
+	var v, dv interface{}
+	for _, a := range attrs {
+		switch a.key {
+		case key:
+			v = a.value
+		case defaultKey:
+			dv = a.value
+		}
+	}
+	if !isNullish(v) {
+		return v, true
+	}
+	if !isNullish(dv) {
+		return dv, true
+	}
+	return nil, false
+}
+
+// withoutAttributes 返回删除了 keys 的属性列表，不会修改原列表
+func withoutAttributes(attrs []attribute, keys ...string) []attribute {
+	r := make([]attribute, 0, len(attrs))
+	for _, a := range attrs {
+		skip := false
+		for _, k := range keys {
+			if a.key == k {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			r = append(r, a)
+		}
+	}
+	return r
+}
+
+// optionSelected 判断 option 的值是否在 select 的值中，select multiple 的值为数组
+func optionSelected(selectValue interface{}, value interface{}) bool {
+	v := fmt.Sprintf("%v", exportValue(value))
+	selected := false
+	if forEachItem(selectValue, func(i interface{}) bool {
+		selected = fmt.Sprintf("%v", exportValue(i)) == v
+		return !selected
+	}) {
+		return selected
+	}
+	return fmt.Sprintf("%v", exportValue(selectValue)) == v
+}
+
+func exportValue(v interface{}) interface{} {
+	if gv, ok := v.(goja.Value); ok {
+		return gv.Export()
+	}
+	return v
+}
+
+// textContent 返回子节点中的文本，用于获取 <option> 的值
+func textContent(c interface{}) string {
+	var b strings.Builder
+	var walk func(c interface{})
+	walk = func(c interface{}) {
+		if isNullish(c) {
+			return
+		}
+		if forEachItem(c, func(i interface{}) bool {
+			walk(i)
+			return true
+		}) {
+			return
+		}
+		if v, ok := c.(goja.Value); ok {
+			if _, ok := v.(*goja.Object); !ok {
+				c = v.Export()
+			}
+		}
+		switch t := c.(type) {
+		case bool:
+		case string:
+			b.WriteString(t)
+		case *Text:
+			b.WriteString(t.Text)
+		default:
+			if _, ok := toNumber(t); ok {
+				b.WriteString(fmt.Sprintf("%v", t))
+			}
+		}
+	}
+	walk(c)
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 期望的结果来自 react-dom/server 的 renderToStaticMarkup
+func TestReactDomConformance(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name string
+		Jsx  string
+		Html string
+	}{
+		{Name: "input value", Jsx: `<input value="a" type="text" defaultValue="b"/>`, Html: `<input type="text" value="a"/>`},
+		{Name: "input defaultValue", Jsx: `<input defaultValue="b"/>`, Html: `<input value="b"/>`},
+		{Name: "input defaultChecked", Jsx: `<input type="checkbox" defaultChecked/>`, Html: `<input type="checkbox" checked=""/>`},
+		{Name: "input checked", Jsx: `<input type="checkbox" checked={false} defaultChecked/>`, Html: `<input type="checkbox"/>`},
+		{Name: "input checked and value", Jsx: `<input value="1" checked type="radio"/>`, Html: `<input type="radio" checked="" value="1"/>`},
+		{Name: "textarea value", Jsx: `<textarea value="a<b" rows={2}/>`, Html: `<textarea rows="2">a&lt;b</textarea>`},
+		{Name: "textarea defaultValue", Jsx: `<textarea defaultValue="x"></textarea>`, Html: `<textarea>x</textarea>`},
+		{Name: "select value", Jsx: `<select value="b"><option value="a">A</option><option value="b">B</option></select>`, Html: `<select><option value="a">A</option><option value="b" selected="">B</option></select>`},
+		{Name: "select defaultValue", Jsx: `<select defaultValue={2}><option value={1}>A</option><option value={2}>B</option></select>`, Html: `<select><option value="1">A</option><option value="2" selected="">B</option></select>`},
+		{Name: "select multiple", Jsx: `<select multiple value={["a", "c"]}><option value="a">A</option><option value="b">B</option><option>c</option></select>`, Html: `<select multiple=""><option value="a" selected="">A</option><option value="b">B</option><option selected="">c</option></select>`},
+		{Name: "select in fragment", Jsx: `<select value="b"><><optgroup label="g"><option>a</option><option>{"b"}</option></optgroup></></select>`, Html: `<select><optgroup label="g"><option>a</option><option selected="">b</option></optgroup></select>`},
+		{Name: "aria and data boolean", Jsx: `<div aria-hidden={true} data-x={false}></div>`, Html: `<div aria-hidden="true" data-x="false"></div>`},
+		{Name: "event handlers", Jsx: `<button onClick={() => 1} onclick="x" type="button">x</button>`, Html: `<button type="button">x</button>`},
+		{Name: "function value", Jsx: `<div title={() => 1}></div>`, Html: `<div></div>`},
+		{Name: "boolean on string attribute", Jsx: `<div foo={true} title={false} hidden={0} tabIndex={-1}></div>`, Html: `<div tabindex="-1"></div>`},
+		{Name: "booleanish string", Jsx: `<div contentEditable={true} draggable={false} spellCheck="true"></div>`, Html: `<div contenteditable="true" draggable="false" spellcheck="true"></div>`},
+		{Name: "overloaded boolean", Jsx: `<p><a download={true}></a><a download="f.txt"></a><a download={false}></a></p>`, Html: `<p><a download=""></a><a download="f.txt"></a><a></a></p>`},
+		{Name: "numeric", Jsx: `<table><tr><td rowSpan={2} colSpan={3}></td></tr></table>`, Html: `<table><tr><td rowspan="2" colSpan="3"></td></tr></table>`},
+		{Name: "positive numeric", Jsx: `<textarea rows={0} cols={3}></textarea>`, Html: `<textarea cols="3"></textarea>`},
+		{Name: "reserved props", Jsx: `<div ref={() => 1} suppressHydrationWarning={true} suppressContentEditableWarning></div>`, Html: `<div></div>`},
+		{Name: "empty string", Jsx: `<img alt="" src="a.png"/>`, Html: `<img alt="" src="a.png"/>`},
+		{Name: "null and undefined", Jsx: `<div title={null} id={undefined}></div>`, Html: `<div></div>`},
+		{Name: "xlink", Jsx: `<svg><a xlinkHref="#a">a</a></svg>`, Html: `<svg><a xlink:href="#a">a</a></svg>`},
+		{Name: "htmlFor and className", Jsx: `<label htmlFor="a" className="c"></label>`, Html: `<label for="a" class="c"></label>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			s, _, err := j.RenderCode([]byte(`export default () => `+c.Jsx), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, c.Html, s)
+		})
+	}
+}
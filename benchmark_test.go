@@ -33,6 +33,84 @@ func BenchmarkName(b *testing.B) {
 	}
 }
 
+var benchmarkRenderCode = []byte(`
+function Item(props) {
+	return <li className="item" data-id={props.id} style={{color: "red", fontSize: "12px"}}>
+		<a href={"/post/" + props.id} title={"post " + props.id}>{props.title}</a>
+	</li>
+}
+export default (props) => <ul>{props.items.map(i => <Item id={i} title={"title " + i}></Item>)}</ul>
+`)
+
+func benchmarkRenderProps() map[string]interface{} {
+	items := make([]int, 200)
+	for i := range items {
+		items[i] = i
+	}
+	return map[string]interface{}{"items": items}
+}
+
+// 渲染前 Export 整个 VDom 树
+//   11,132,405 ns/op	 1,905,393 B/op	   34,035 allocs/op
+func BenchmarkRenderExport(b *testing.B) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	props := benchmarkRenderProps()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ex, err := j.ExecCode(benchmarkRenderCode, WithAutoExecJsx(props))
+		if err != nil {
+			b.Fatal(err)
+		}
+		ex.Default.(VDom).Render()
+	}
+}
+
+// 直接从 goja.Object 渲染，不再为每个节点创建 map，属性保持源码中的顺序
+//   10,979,172 ns/op	 1,517,986 B/op	   33,476 allocs/op
+// 总的 allocs 只减少了约 1.6%：约 2/3 来自执行 js（callDefaultExport，两者相同），
+// 渲染的约 10,400 次中约 8,400 次来自 goja.Object.Keys（每个属性约 4 次），goja 没有更省的 API，只有 map 的 allocs 被去掉了
+func BenchmarkRenderGoja(b *testing.B) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	props := benchmarkRenderProps()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := j.RenderCode(benchmarkRenderCode, props)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 插件只转换元素的属性，子节点依然直接从 goja.Object 渲染
+//    6,073,496 ns/op	 1,666,697 B/op	   36,119 allocs/op
+// 之前为每个元素转换整个子树时：
+//    6,935,777 ns/op	 2,211,071 B/op	   38,852 allocs/op
+func BenchmarkRenderPlugins(b *testing.B) {
+	j, err := NewJsx(Option{RenderPlugins: []RenderPlugin{func(el *Element) (Node, error) {
+		return el, nil
+	}}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	props := benchmarkRenderProps()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := j.RenderCode(benchmarkRenderCode, props)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestOne(t *testing.T) {
 	j, err := NewJsx(Option{})
 	j.debug = true
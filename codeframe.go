@@ -0,0 +1,164 @@
+package gojsx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/text/width"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sourceFiles 保存加载过的源码（转换之前的 .tsx、.mdx 等），用于在报错时显示代码片段。
+// 调用栈中的位置已经由 goja 通过 sourcemap 转换为源码中的位置。
+type sourceFiles struct {
+	m sync.Map // path => []byte
+}
+
+func (s *sourceFiles) set(name string, src []byte) {
+	s.m.Store(path.Clean(name), src)
+}
+
+func (s *sourceFiles) get(name string) ([]byte, bool) {
+	v, ok := s.m.Load(path.Clean(name))
+	if !ok {
+		return nil, false
+	}
+	return v.([]byte), true
+}
+
+var sourceMappingURLPrefix = []byte("\n//# sourceMappingURL=")
+
+// appendBeforeSourceMap 在代码末尾追加 js，如果有 sourceMappingURL 注释则追加在它之前，否则 goja 找不到 sourcemap
+func appendBeforeSourceMap(code []byte, js []byte) []byte {
+	i := bytes.LastIndex(code, sourceMappingURLPrefix)
+	if i == -1 {
+		return append(code[:len(code):len(code)], js...)
+	}
+	r := make([]byte, 0, len(code)+len(js)+1)
+	r = append(r, code[:i]...)
+	r = append(r, js...)
+	r = append(r, code[i:]...)
+	return r
+}
+
+// codeFrameContext 是代码片段中错误所在行前后的行数
+const codeFrameContext = 2
+
+// codeFrame 返回 line 行（从 1 开始）column 列（从 0 开始）附近的代码，如：
+//
+//	  3 | function Widget() {
+//	> 4 | 	throw new Error("x")
+//	    | 	      ^
+//	  5 | }
+func codeFrame(src []byte, line, column int) string {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	start := line - codeFrameContext
+	if start < 1 {
+		start = 1
+	}
+	end := line + codeFrameContext
+	if end > len(lines) {
+		end = len(lines)
+	}
+	width := len(strconv.Itoa(end))
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		l := strings.TrimRight(lines[i-1], "\r")
+		if i == line {
+			b.WriteString(">")
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString(fmt.Sprintf(" %*d | %s\n", width, i, l))
+
+		if i == line {
+			if indent, ok := caretIndent(l, column); ok {
+				b.WriteString(fmt.Sprintf("  %*s | %s^\n", width, "", indent))
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// caretIndent 返回 ^ 之前的空白，使 ^ 与 line 中的 column 列对齐，column 超出 line 时返回 false。
+// column 以 UTF-16 编码单元计算（与 sourcemap 和 js 一致），保留 tab，宽字符（如中文）使用两个空格。
+func caretIndent(line string, column int) (string, bool) {
+	var b strings.Builder
+	units := 0
+	for _, r := range line {
+		if units >= column {
+			break
+		}
+		switch {
+		case r == '\t':
+			b.WriteByte('\t')
+		case isWideRune(r):
+			b.WriteString("  ")
+		default:
+			b.WriteByte(' ')
+		}
+		units += utf16Len(r)
+	}
+	return b.String(), units >= column
+}
+
+// utf16Len 返回 r 的 UTF-16 编码单元数量
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// utf16Column 将 s 中的字节偏移转为 UTF-16 编码单元的列
+func utf16Column(s string, offset int) int {
+	column := 0
+	for _, r := range s[:offset] {
+		column += utf16Len(r)
+	}
+	return column
+}
+
+// isWideRune 返回 r 在等宽字体中是否占两列
+func isWideRune(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
+
+// withCodeFrame 为 Exception 添加代码片段，使用调用栈中第一个能找到源码的位置。
+// root 与 rootSrc 是直接执行的代码（如 ExecCode 的参数），它们不会被保存在 sourceFiles 中。
+func (j *Jsx) withCodeFrame(err error, root string, rootSrc []byte) error {
+	var ex *Exception
+	if !errors.As(err, &ex) || ex.CodeFrame != "" {
+		return err
+	}
+
+	for _, f := range ex.Frames {
+		if f.Native {
+			continue
+		}
+		src, ok := j.sources.get(f.File)
+		if path.Clean(f.File) == path.Clean(root) {
+			src, ok = rootSrc, true
+		}
+		if !ok {
+			continue
+		}
+		ex.CodeFrame = codeFrame(src, f.Line, f.Column)
+		if ex.CodeFrame != "" {
+			break
+		}
+	}
+	return err
+}
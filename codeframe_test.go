@@ -0,0 +1,107 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCodeFrame(t *testing.T) {
+	src := []byte("function Widget() {\n\tthrow new Error(\"x\")\n}\n")
+	assert.Equal(t, `  1 | function Widget() {
+> 2 | 	throw new Error("x")
+    | 	      ^
+  3 | }
+  4 | `, codeFrame(src, 2, 7))
+	assert.Equal(t, "", codeFrame(src, 10, 0))
+
+	// 列以 UTF-16 编码单元计算，宽字符对应两个空格
+	src = []byte("const s = \"中文😀\"; throw s")
+	assert.Equal(t, "> 1 | const s = \"中文😀\"; throw s\n    | "+strings.Repeat(" ", 20)+"^", codeFrame(src, 1, 18))
+}
+
+func TestSourceMappedException(t *testing.T) {
+	j, err := NewJsx(Option{Fs: fstest.MapFS{
+		"Page.tsx": {Data: []byte(`interface Props {
+  name: string
+}
+
+export default function Page(props: Props) {
+  const a: number = 1
+  return <div>{(props as any).missing.field}</div>
+}
+`)},
+		"Cjk.tsx": {Data: []byte(`export default function Cjk() {
+  const title = "标题"; return <div>{(title as any).missing.field}</div>
+}
+`)},
+		"CjkDoc.mdx": {Data: []byte(`export function Broken() {
+  const s = "中文"; return props.missing.field
+}
+`)},
+		"Doc.mdx": {Data: []byte(`---
+title: Doc
+---
+
+export function Broken() {
+  return props.missing.field
+}
+
+# Title
+
+Some text with {props.a.b} here.
+`)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = j.Render("./Page", nil)
+	var ex *Exception
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, Frame{Function: "Page", File: "Page.tsx", Line: 7, Column: 30}, ex.Frames[0])
+		assert.Equal(t, `  5 | export default function Page(props: Props) {
+  6 |   const a: number = 1
+> 7 |   return <div>{(props as any).missing.field}</div>
+    |                               ^
+  8 | }
+  9 | `, ex.CodeFrame)
+	}
+
+	// markdown 中的表达式映射到行
+	_, err = j.Render("./Doc.mdx", map[string]interface{}{})
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Contains(t, ex.Error(), "at Doc_default (Doc.mdx:11:0)")
+		assert.Contains(t, ex.CodeFrame, "> 11 | Some text with {props.a.b} here.")
+	}
+
+	// 代码块中的 js 映射到列
+	_, _, err = j.RenderCode([]byte(`import {Broken} from "./Doc.mdx"
+export default () => <Broken/>`), nil)
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Contains(t, ex.Error(), "at Broken (Doc.mdx:6:9)")
+		assert.Contains(t, ex.CodeFrame, "> 6 |   return props.missing.field\n    |          ^")
+	}
+
+	// 源码中的宽字符
+	_, err = j.Render("./Cjk", nil)
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, Frame{Function: "Cjk", File: "Cjk.tsx", Line: 2, Column: 58}, ex.Frames[0])
+		assert.Contains(t, ex.CodeFrame, "> 2 |   const title = \"标题\"; return <div>{(title as any).missing.field}</div>\n    | "+strings.Repeat(" ", 60)+"^")
+	}
+	_, _, err = j.RenderCode([]byte(`import {Broken} from "./CjkDoc.mdx"
+export default () => <Broken/>`), nil)
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Contains(t, ex.Error(), "at Broken (CjkDoc.mdx:2:25)")
+		assert.Contains(t, ex.CodeFrame, "> 2 |   const s = \"中文\"; return props.missing.field\n    | "+strings.Repeat(" ", 27)+"^")
+	}
+
+	// 直接执行的代码
+	_, _, err = j.RenderCode([]byte(`
+
+export default () => <p>{null.x}</p>`), nil, WithFileName("root.tsx"))
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Contains(t, ex.CodeFrame, "> 3 | export default () => <p>{null.x}</p>")
+	}
+}
@@ -0,0 +1,166 @@
+package gojsx
+
+import (
+	"fmt"
+	"github.com/dop251/goja"
+	"github.com/zbysir/gojsx/internal/pkg/goja_nodejs/require"
+	"path"
+)
+
+// Props 是组件的属性，不包含 children
+type Props map[string]interface{}
+
+// Component 是使用 Go 实现的组件，children 是组件的子节点。
+// children 中 reject 的 Promise（如 async 组件）作为组件的错误抛出，没有完成的 Promise 返回 ErrPromisePending。
+type Component func(props Props, children []Node) (Node, error)
+
+// ComponentError 是 Go 组件返回的错误，在 js 中会作为异常抛出，可以被 js 捕获。
+type ComponentError struct {
+	Component string
+	Err       error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("component <%s>: %v", e.Component, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterComponent 注册一个 Go 组件，name 是导入路径，组件是它的默认导出：
+//
+//	j.RegisterComponent("chart", func(props gojsx.Props, children []gojsx.Node) (gojsx.Node, error) {...})
+//
+//	import Chart from "chart"
+//	export default () => <Chart data={[1, 2, 3]}>title</Chart>
+//
+// Component 也可以作为 RegisterModule、WithNativeModule 中的值，用于在一个模块中导出多个组件。
+func (j *Jsx) RegisterComponent(name string, c Component) {
+	require.RegisterNativeModule(name, func(runtime *goja.Runtime, module *goja.Object) {
+		o := module.Get("exports").(*goja.Object)
+		_ = o.Set("__esModule", true)
+		_ = o.Set("default", wrapComponent(runtime, componentName(name), c))
+	})
+}
+
+// componentName 使用导入路径的最后一段作为组件名，用于错误信息
+func componentName(name string) string {
+	return path.Base(name)
+}
+
+// moduleValue 将模块中的 Component 包装为 js 函数，其他值不变
+func moduleValue(vm *goja.Runtime, name string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case Component:
+		return wrapComponent(vm, name, t)
+	case func(props Props, children []Node) (Node, error):
+		return wrapComponent(vm, name, t)
+	}
+	return v
+}
+
+// wrapComponent 将 Go 组件包装为 js 函数组件，参数是 jsx() 传入的 attributes，返回 VDom 结构的 js 对象。
+func wrapComponent(vm *goja.Runtime, name string, c Component) goja.Value {
+	f := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		props := Props{}
+		var children []Node
+		if o, ok := call.Argument(0).(*goja.Object); ok {
+			for _, k := range o.Keys() {
+				v := o.Get(k)
+				if k == "children" {
+					// 组件在 js 执行中被调用，job 队列不会执行，只能得到已经完成的 Promise（如 async 组件同步抛出的错误）
+					c, err := toNode(v, func(p *goja.Promise) (goja.Value, error) {
+						return resolvePromise(vm, p)
+					})
+					if err != nil {
+						panic(vm.NewGoError(&ComponentError{Component: name, Err: err}))
+					}
+					children = nodeList(c)
+					continue
+				}
+				props[k] = v.Export()
+			}
+		}
+
+		n, err := c(props, children)
+		if err != nil {
+			panic(vm.NewGoError(&ComponentError{Component: name, Err: err}))
+		}
+		return nodeToValue(vm, n)
+	}).(*goja.Object)
+	_ = f.DefineDataProperty("name", vm.ToValue(name), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	return f
+}
+
+// nodeList 将子节点转为列表，Fragment 会被展开
+func nodeList(n Node) []Node {
+	switch t := n.(type) {
+	case nil:
+		return nil
+	case *Fragment:
+		if !t.head && !t.cdata {
+			return t.Children
+		}
+	}
+	return []Node{n}
+}
+
+// nodeToValue 将 Node 转为 VDom 结构的 js 对象，与 jsx() 的返回值一致
+func nodeToValue(vm *goja.Runtime, n Node) goja.Value {
+	switch t := n.(type) {
+	case nil:
+		return goja.Null()
+	case *Text:
+		return vm.ToValue(t.Text)
+	case *RawHTML:
+		o := vm.NewObject()
+		_ = o.Set("__dangerousHTML", t.HTML)
+		return o
+	case *Fragment:
+		o := vm.NewObject()
+		_ = o.Set("nodeName", "")
+		_ = o.Set("attributes", childrenValue(vm, vm.NewObject(), t.Children))
+		if t.head {
+			_ = o.Set("__head", true)
+		}
+		if t.cdata {
+			_ = o.Set("__cdata", true)
+		}
+		if t.caught != nil {
+			_ = o.Set("__caught", t.caught)
+		}
+		if t.boundary {
+			_ = o.Set("__boundary", map[string]interface{}{"fallback": t.fallback})
+		}
+		return o
+	case *Element:
+		attrs := vm.NewObject()
+		for _, a := range t.Attributes {
+			_ = attrs.Set(a.Key, a.Value)
+		}
+		o := vm.NewObject()
+		_ = o.Set("nodeName", t.NodeName)
+		_ = o.Set("attributes", childrenValue(vm, attrs, t.Children))
+		if t.Key != nil {
+			_ = o.Set("key", t.Key)
+		}
+		if t.island != nil {
+			_ = o.Set("__island", t.island)
+		}
+		return o
+	}
+	return goja.Undefined()
+}
+
+func childrenValue(vm *goja.Runtime, attrs *goja.Object, children []Node) *goja.Object {
+	if len(children) == 0 {
+		return attrs
+	}
+	cs := make([]interface{}, len(children))
+	for i, c := range children {
+		cs[i] = nodeToValue(vm, c)
+	}
+	_ = attrs.Set("children", vm.NewArray(cs...))
+	return attrs
+}
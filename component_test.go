@@ -0,0 +1,153 @@
+package gojsx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRegisterComponent(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j.RegisterComponent("@test/chart", func(props Props, children []Node) (Node, error) {
+		bars := make([]Node, 0)
+		for _, d := range props["data"].([]interface{}) {
+			bars = append(bars, &Element{NodeName: "rect", Attributes: []Attribute{{Key: "height", Value: d}}})
+		}
+		return &Element{
+			NodeName:   "figure",
+			Attributes: []Attribute{{Key: "className", Value: props["className"]}},
+			Children: []Node{
+				&Element{NodeName: "svg", Children: bars},
+				&Element{NodeName: "figcaption", Children: children},
+			},
+		}, nil
+	})
+
+	s, _, err := j.RenderCode([]byte(`
+import Chart from "@test/chart"
+export default () => <Chart className="chart" data={[1, 2]}><b>Title</b>{"!"}</Chart>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<figure class="chart"><svg><rect height="1"/><rect height="2"/></svg><figcaption><b>Title</b>!</figcaption></figure>`, s)
+}
+
+func TestComponentInModule(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j.RegisterModule("@test/ui", map[string]interface{}{
+		"Badge": Component(func(props Props, children []Node) (Node, error) {
+			return &Element{NodeName: "span", Attributes: []Attribute{{Key: "class", Value: "badge"}}, Children: children}, nil
+		}),
+		"Raw": func(props Props, children []Node) (Node, error) {
+			return &RawHTML{HTML: fmt.Sprintf("<%s>", props["tag"])}, nil
+		},
+	})
+
+	s, _, err := j.RenderCode([]byte(`
+import {Badge, Raw} from "@test/ui"
+export default () => <div><Badge>new</Badge><Raw tag="hr"/></div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div><span class="badge">new</span><hr></div>`, s)
+
+	// 在其他组件中使用返回的 VDom
+	s, _, err = j.RenderCode([]byte(`
+import {Badge} from "@test/ui"
+export default () => { const b = <Badge>a</Badge>; return <p>{b.nodeName}:{b.attributes.children}</p> }`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<p>span:a</p>`, s)
+}
+
+var errNoData = errors.New("no data")
+
+func TestComponentError(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j.RegisterComponent("@test/fail", func(props Props, children []Node) (Node, error) {
+		return nil, errNoData
+	})
+
+	// 可以在 js 中捕获
+	s, _, err := j.RenderCode([]byte(`
+import Fail from "@test/fail"
+export default () => { try { return Fail({}) } catch (e) { return <p>{e.message}</p> } }`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<p>component &lt;fail&gt;: no data</p>`, s)
+
+	_, _, err = j.RenderCode([]byte(`
+import Fail from "@test/fail"
+export default () => <div><Fail/></div>`), nil)
+	assert.Contains(t, err.Error(), "GoError: component <fail>: no data")
+	var ce *ComponentError
+	if assert.ErrorAs(t, err, &ce) {
+		assert.Equal(t, "fail", ce.Component)
+	}
+	assert.ErrorIs(t, err, errNoData)
+
+	// 被 <ErrorBoundary> 捕获
+	_, ctx, err := j.RenderCode([]byte(`
+import Fail from "@test/fail"
+import {ErrorBoundary} from "gojsx"
+export default () => <ErrorBoundary fallback="x"><Fail/></ErrorBoundary>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Equal(t, 1, len(ctx.Errors)) {
+		assert.ErrorIs(t, ctx.Errors[0], errNoData)
+	}
+}
+
+func TestComponentAsyncChildren(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RegisterComponent("@test/box", func(props Props, children []Node) (Node, error) {
+		return &Element{NodeName: "section", Children: children}, nil
+	})
+
+	// 同步完成的 async 组件可以作为子节点
+	s, _, err := j.RenderCode([]byte(`
+import Box from "@test/box"
+async function Ok() { return <p>ok</p> }
+export default () => <Box><Ok/></Box>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<section><p>ok</p></section>`, s)
+
+	// reject 的子节点作为组件的错误返回，而不是被忽略
+	_, _, err = j.RenderCode([]byte(`
+import Box from "@test/box"
+async function Bad() { throw new Error("bad") }
+export default () => <Box><Bad/></Box>`), nil)
+	var ce *ComponentError
+	if assert.ErrorAs(t, err, &ce) {
+		assert.Equal(t, "box", ce.Component)
+		assert.ErrorContains(t, err, "bad")
+	}
+
+	// 没有完成的子节点
+	_, _, err = j.RenderCode([]byte(`
+import Box from "@test/box"
+async function Slow() { await null; return <p>slow</p> }
+export default () => <Box><Slow/></Box>`), nil)
+	assert.ErrorIs(t, err, ErrPromisePending)
+}
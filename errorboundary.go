@@ -0,0 +1,97 @@
+package gojsx
+
+import (
+	"errors"
+	"github.com/dop251/goja"
+	"strings"
+)
+
+// pendingErrorsKey 是 jsx-runtime 中记录组件抛出的错误的全局变量，见 jsx-runtime.js 中的 callComponent
+const pendingErrorsKey = "__gojsxPendingErrors"
+
+// pendingError 在执行 js 之后调用，如果有组件抛出的错误没有被 <ErrorBoundary> 捕获，则返回第一个错误。
+func pendingError(vm *goja.Runtime) error {
+	errs, ok := vm.Get(pendingErrorsKey).(*goja.Object)
+	if !ok {
+		return nil
+	}
+	clearPendingErrors(vm)
+	if errs.Get("length").ToInteger() == 0 {
+		return nil
+	}
+
+	// 在 js 中重新抛出，得到包含原始调用栈的 *goja.Exception
+	e := errs.Get("0").(*goja.Object).Get("__error")
+	throw, _ := goja.AssertFunction(vm.ToValue(func(goja.FunctionCall) goja.Value {
+		panic(e)
+	}))
+	_, err := throw(nil)
+	return PrettifyException(err)
+}
+
+func clearPendingErrors(vm *goja.Runtime) {
+	_ = vm.GlobalObject().Delete(pendingErrorsKey)
+}
+
+// addError 记录被 <ErrorBoundary> 捕获的错误，caught 见 jsx-runtime.js 中的 ErrorBoundary
+func (ctx *RenderCtx) addError(caught map[string]interface{}) {
+	ctx.Errors = append(ctx.Errors, newException(caught))
+}
+
+// renderBoundary 渲染没有捕获到错误的 <ErrorBoundary> 的子节点，子节点中的 Promise（如 async 组件）reject 时渲染 fallback。
+// 子节点先渲染到缓冲区，失败时丢弃已经渲染的内容。
+func renderBoundary(s *renderWriter, ctx *RenderCtx, n *vnode) {
+	var b strings.Builder
+	w := &renderWriter{w: &b}
+	if s.max > 0 {
+		w.max = s.max - s.written
+	}
+	islands, caught := len(ctx.Islands), len(ctx.Errors)
+	if !isNullish(n.children) {
+		renderChildren(w, ctx, n, n.children)
+	}
+
+	var ex *Exception
+	if w.err == nil || !errors.As(w.err, &ex) || ex.reason == nil {
+		if w.err != nil {
+			s.err = w.err
+			return
+		}
+		s.WriteString(b.String())
+		return
+	}
+
+	ctx.Islands = ctx.Islands[:islands]
+	ctx.Errors = append(ctx.Errors[:caught], ex)
+	fallback, err := callFallback(ctx.vm, n.fallback, ex.reason)
+	if err != nil {
+		s.err = err
+		return
+	}
+	render(s, ctx, fallback)
+}
+
+// callFallback 返回 fallback 渲染的内容，fallback 是函数时使用错误调用它
+func callFallback(vm *goja.Runtime, fallback interface{}, reason goja.Value) (interface{}, error) {
+	if f, ok := fallback.(func(goja.FunctionCall) goja.Value); ok && vm != nil {
+		fallback = vm.ToValue(f)
+	}
+	v, ok := fallback.(goja.Value)
+	if !ok {
+		return fallback, nil
+	}
+	f, ok := goja.AssertFunction(v)
+	if !ok {
+		return fallback, nil
+	}
+	r, err := f(nil, reason)
+	if err == nil {
+		// fallback 中的组件抛出的错误
+		err = pendingError(vm)
+	}
+	if err != nil {
+		// 可以被外层的 <ErrorBoundary> 捕获
+		return nil, PrettifyException(err)
+	}
+	return r, nil
+}
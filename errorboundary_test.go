@@ -0,0 +1,161 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorBoundary(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ctx, err := j.RenderCode([]byte(`
+import {ErrorBoundary} from "gojsx"
+
+function Widget() {
+	throw new Error("widget is broken")
+}
+
+function Sidebar({children, widget}) {
+	return <aside>{children}{widget}</aside>
+}
+
+export default () => <main>
+	<article>content</article>
+	<ErrorBoundary fallback={<p>sidebar is unavailable</p>}>
+		<Sidebar><Widget/></Sidebar>
+	</ErrorBoundary>
+	<ErrorBoundary fallback={(e) => <p>{e.message}</p>}>
+		<Sidebar widget={<Widget/>}/>
+	</ErrorBoundary>
+	<ErrorBoundary fallback={<p>never</p>}>
+		<Sidebar>ok</Sidebar>
+	</ErrorBoundary>
+</main>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<main><article>content</article><p>sidebar is unavailable</p><p>widget is broken</p><aside>ok</aside></main>`, s)
+	if assert.Equal(t, 2, len(ctx.Errors)) {
+		assert.Contains(t, ctx.Errors[0].Error(), "Error: widget is broken\n\tat Widget (index.jsx:5:7)")
+	}
+}
+
+func TestErrorBoundaryNested(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 错误被最近的 <ErrorBoundary> 捕获，fallback 中的错误被外层捕获
+	s, ctx, err := j.RenderCode([]byte(`
+import {ErrorBoundary} from "gojsx"
+
+const Fail = ({msg}) => { throw new Error(msg) }
+
+export default () => <div>
+	<ErrorBoundary fallback="outer">
+		<ErrorBoundary fallback="inner"><Fail msg="a"/></ErrorBoundary>
+		<ErrorBoundary fallback={() => <Fail msg="c"/>}><Fail msg="b"/></ErrorBoundary>
+	</ErrorBoundary>
+</div>`), nil, WithPretty(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div>outer</div>`, s)
+	assert.Equal(t, 1, len(ctx.Errors))
+}
+
+func TestUncaughtComponentError(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有被 <ErrorBoundary> 捕获的错误依然会导致执行失败
+	_, _, err = j.RenderCode([]byte(`
+import {ErrorBoundary} from "gojsx"
+
+const Fail = () => { throw new Error("oops") }
+
+export default () => <div>
+	<ErrorBoundary fallback="x"><span/></ErrorBoundary>
+	<Fail/>
+</div>`), nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Error: oops\n\tat Fail (index.jsx:4:27)")
+	}
+
+	_, err = j.ExecCode([]byte(`
+const Fail = () => { throw new Error("oops") }
+export default () => <div><Fail/></div>`), WithAutoExecJsx(nil))
+	assert.Error(t, err)
+
+	// 同一个 vm 的下一次执行不受影响
+	s, _, err := j.RenderCode([]byte(`export default () => <div/>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div></div>`, s)
+}
+
+func TestErrorBoundaryAsync(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := []byte(`
+import {ErrorBoundary, Head} from "gojsx"
+
+async function Widget() {
+	await null
+	throw new Error("async boom")
+}
+
+async function Ok() {
+	await null
+	return <aside><Head><title>ok</title></Head>ok</aside>
+}
+
+const Fail = () => { throw new Error("fallback boom") }
+
+export default () => <html><head></head><body>
+	<ErrorBoundary fallback={<p>unavailable</p>}>
+		<section><Head><title>widget</title></Head><Widget/></section>
+	</ErrorBoundary>
+	<ErrorBoundary fallback={(e) => <p>{e.message}</p>}>
+		<div><Widget/></div>
+	</ErrorBoundary>
+	<ErrorBoundary fallback="outer">
+		<ErrorBoundary fallback={() => <Fail/>}><Widget/></ErrorBoundary>
+	</ErrorBoundary>
+	<ErrorBoundary fallback="never"><Ok/></ErrorBoundary>
+</body></html>`)
+
+	s, ctx, err := j.RenderCode(code, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<!DOCTYPE html><html><head><title>ok</title></head><body><p>unavailable</p><p>async boom</p>outer<aside>ok</aside></body></html>`, s)
+	if assert.Equal(t, 3, len(ctx.Errors)) {
+		assert.Contains(t, ctx.Errors[0].Error(), "Error: async boom\n\tat Widget (index.jsx:6:7)")
+		assert.Contains(t, ctx.Errors[2].Error(), "Error: fallback boom")
+	}
+
+	// 使用 RenderPlugin 时同样被捕获
+	s, _, err = j.RenderCode(code, nil, WithRenderPlugins(func(el *Element) (Node, error) { return el, nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<!DOCTYPE html><html><head><title>ok</title></head><body><p>unavailable</p><p>async boom</p>outer<aside>ok</aside></body></html>`, s)
+
+	// 没有被捕获的 async 组件的错误依然会导致渲染失败
+	_, _, err = j.RenderCode([]byte(`
+import {ErrorBoundary} from "gojsx"
+async function Widget() { await null; throw new Error("async boom") }
+export default () => <div><ErrorBoundary fallback="x"><span/></ErrorBoundary><Widget/></div>`), nil)
+	assert.ErrorContains(t, err, "Error: async boom")
+}
@@ -0,0 +1,52 @@
+package gojsx
+
+import (
+	"github.com/dop251/goja"
+)
+
+// disableEvalScript 替换所有可以将字符串作为代码执行的函数：
+//   - eval
+//   - Function 构造函数，以及通过 (function(){}).constructor 等方式得到的 AsyncFunction、GeneratorFunction
+//   - 参数为字符串的 setTimeout、setInterval（goja 没有提供它们，但可能被添加到全局变量中）
+//
+// 替换之后的 Function 依然可以用于 instanceof。
+const disableEvalScript = `(function () {
+    function disabled(name) {
+        return function () {
+            throw new EvalError("code generation from strings is disabled: " + name)
+        }
+    }
+    function lock(o, key, value) {
+        Object.defineProperty(o, key, {value: value, writable: false, enumerable: false, configurable: false})
+    }
+    function lockConstructor(name, proto) {
+        const f = disabled(name)
+        Object.defineProperty(f, "prototype", {value: proto, writable: false})
+        lock(proto, "constructor", f)
+        return f
+    }
+
+    lock(globalThis, "Function", lockConstructor("Function", Function.prototype))
+    lockConstructor("AsyncFunction", Object.getPrototypeOf(async function () {}))
+    lockConstructor("GeneratorFunction", Object.getPrototypeOf(function* () {}))
+    lock(globalThis, "eval", disabled("eval"))
+
+    for (const name of ["setTimeout", "setInterval", "setImmediate"]) {
+        const timer = globalThis[name]
+        if (typeof timer !== "function") {
+            continue
+        }
+        lock(globalThis, name, function (handler, ...args) {
+            if (typeof handler !== "function") {
+                disabled(name + " with a string")()
+            }
+            return timer.call(this, handler, ...args)
+        })
+    }
+})()`
+
+// disableEval 禁止 vm 中的 js 将字符串作为代码执行，见 Option.DisableEval
+func disableEval(vm *goja.Runtime) error {
+	_, err := vm.RunString(disableEvalScript)
+	return err
+}
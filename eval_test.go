@@ -0,0 +1,75 @@
+package gojsx
+
+import (
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDisableEval(t *testing.T) {
+	j, err := NewJsx(Option{DisableEval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Code string
+		Name string
+	}{
+		{`eval("1 + 1")`, "eval"},
+		{`(0, eval)("1 + 1")`, "eval"},
+		{`globalThis.eval("1 + 1")`, "eval"},
+		{`Function("return 1")()`, "Function"},
+		{`new Function("return 1")()`, "Function"},
+		{`(function () {}).constructor("return 1")()`, "Function"},
+		{`Object.getPrototypeOf(() => {}).constructor("return 1")()`, "Function"},
+		{`(async function () {}).constructor("return 1")()`, "AsyncFunction"},
+		{`(function* () {}).constructor("yield 1")().next()`, "GeneratorFunction"},
+	}
+	for _, c := range cases {
+		_, err := j.ExecCode([]byte(`export default ` + c.Code))
+		var ex *Exception
+		if assert.ErrorAs(t, err, &ex, c.Code) {
+			assert.Equal(t, "EvalError", ex.Name, c.Code)
+			assert.Equal(t, "code generation from strings is disabled: "+c.Name, ex.Message, c.Code)
+		}
+	}
+
+	// 不能恢复
+	_, err = j.ExecCode([]byte(`
+Function.prototype.constructor = function () {}
+globalThis.eval = (s) => s
+export default eval("1")`))
+	assert.Error(t, err)
+
+	// 不影响其他代码
+	s, _, err := j.RenderCode([]byte(`
+const Async = async () => { await null; return <b>async</b> }
+function* gen() { yield 1; yield 2 }
+export default () => <p>{[...gen()].join(",")} {String((() => {}) instanceof Function)} <Async/></p>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>1,2 true <b>async</b></p>", s)
+}
+
+func TestDisableEvalTimer(t *testing.T) {
+	vm := goja.New()
+	called := 0
+	_ = vm.Set("setTimeout", func(f goja.Callable) {
+		called++
+		_, _ = f(nil)
+	})
+	if err := disableEval(vm); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := vm.RunString(`setTimeout("globalThis.x = 1")`)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "EvalError: code generation from strings is disabled: setTimeout with a string")
+	}
+	_, err = vm.RunString(`setTimeout(() => { globalThis.x = 1 })`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, called)
+	assert.Equal(t, int64(1), vm.Get("x").ToInteger())
+}
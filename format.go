@@ -0,0 +1,371 @@
+package gojsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dop251/goja"
+	"github.com/evanw/esbuild/pkg/api"
+	"regexp"
+	"strings"
+)
+
+type prettyOption bool
+
+func (p prettyOption) applyRenderOptions(options *renderOptions) {
+	options.Pretty = bool(p)
+}
+
+func (p prettyOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, p)
+}
+
+// WithPretty 输出缩进后的 html，用于调试与 golden file 测试。
+// 只有当子节点都是块级元素时才会换行缩进，所以不会改变页面的渲染结果，pre、textarea、script、style 中的内容保持不变。
+func WithPretty(pretty bool) interface {
+	OptionExec
+	OptionRender
+} {
+	return prettyOption(pretty)
+}
+
+type minifyOption bool
+
+func (m minifyOption) applyRenderOptions(options *renderOptions) {
+	options.Minify = bool(m)
+}
+
+func (m minifyOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, m)
+}
+
+// WithMinify 输出压缩后的 html，用于生产环境：
+//   - 省略可选的结束标签，如 </li>、</p>、</td>
+//   - 省略属性值的引号，布尔属性省略值，如 disabled
+//   - 合并 pre、textarea 之外的连续空白字符
+//   - 使用 esbuild 压缩 <style>、<script> 中的 css 与 js
+//
+// 与 WithPretty 同时使用时 WithMinify 优先；在 xml 模式中只会合并空白字符与压缩 css、js。
+func WithMinify(minify bool) interface {
+	OptionExec
+	OptionRender
+} {
+	return minifyOption(minify)
+}
+
+const prettyIndent = "  "
+
+// whitespaceSensitive 中的空白字符需要保持不变
+var whitespaceSensitive = map[string]bool{
+	"pre":       true,
+	"textarea":  true,
+	"listing":   true,
+	"plaintext": true,
+	"script":    true,
+	"style":     true,
+}
+
+// inlineElements 是行内元素，在它们之间添加空白字符会改变渲染结果
+var inlineElements = map[string]bool{
+	"a": true, "abbr": true, "acronym": true, "b": true, "bdi": true, "bdo": true, "big": true, "br": true,
+	"button": true, "cite": true, "code": true, "data": true, "del": true, "dfn": true, "em": true, "i": true,
+	"img": true, "input": true, "ins": true, "kbd": true, "label": true, "mark": true, "math": true, "meter": true,
+	"output": true, "picture": true, "progress": true, "q": true, "ruby": true, "s": true, "samp": true,
+	"select": true, "small": true, "span": true, "strike": true, "strong": true, "sub": true, "sup": true,
+	"svg": true, "textarea": true, "time": true, "tt": true, "u": true, "var": true, "video": true, "audio": true,
+	"wbr": true, "gojsx-island": true,
+}
+
+// child 是展开 Fragment 与数组后的一个子节点
+type child struct {
+	value interface{}
+	// name 是元素的 nodeName，文本为 "#text"，其他（如 CDATA、原始 html）为 "#other"，<Head> 为空
+	name string
+}
+
+// flattenChildren 展开 Fragment 与数组，忽略不会渲染的节点（如 null、bool）
+func flattenChildren(s *renderWriter, ctx *RenderCtx, c interface{}, children []child) []child {
+	if isNullish(c) {
+		return children
+	}
+	switch t := c.(type) {
+	case bool:
+		return children
+	case string:
+		if t == "" {
+			return children
+		}
+		return append(children, child{value: t, name: "#text"})
+	case *goja.Promise:
+		v, err := resolvePromise(ctx.vm, t)
+		if err != nil {
+			s.err = err
+			return children
+		}
+		return flattenChildren(s, ctx, v, children)
+	case *goja.Object:
+		if isPromise(t) {
+			return flattenChildren(s, ctx, t.Export(), children)
+		}
+		if t.ClassName() == "Function" {
+			return children
+		}
+	case goja.Value:
+		return flattenChildren(s, ctx, t.Export(), children)
+	}
+
+	if forEachItem(c, func(i interface{}) bool {
+		children = flattenChildren(s, ctx, i, children)
+		return s.err == nil
+	}) {
+		return children
+	}
+
+	n, ok := toVNode(c)
+	if !ok {
+		return append(children, child{value: c, name: "#text"})
+	}
+	switch {
+	case n.head:
+		return append(children, child{value: c})
+	case n.cdata, n.isHTML:
+		return append(children, child{value: c, name: "#other"})
+	case n.nodeName == "" && n.island == nil:
+		if n.caught != nil {
+			ctx.addError(n.caught)
+		}
+		return flattenChildren(s, ctx, n.children, children)
+	}
+	return append(children, child{value: c, name: n.nodeName})
+}
+
+// renderChildren 渲染 n 的子节点，在 pretty 与 minify 模式中需要知道所有的子节点（如判断是否都是块级元素、下一个兄弟节点）
+func renderChildren(s *renderWriter, ctx *RenderCtx, n *vnode, c interface{}) {
+	if !ctx.pretty && !ctx.minify {
+		render(s, ctx, c)
+		return
+	}
+
+	children := flattenChildren(s, ctx, c, nil)
+	block := ctx.prettyPrint() && ctx.preserve == 0 && isBlockChildren(children)
+	if block && n.nodeName != "" {
+		ctx.depth++
+	}
+
+	for i, ch := range children {
+		if block && (i != 0 || n.nodeName != "") {
+			ctx.newline(s)
+		}
+		if ctx.minify {
+			ctx.parent = n.nodeName
+			ctx.next = nextSibling(children[i+1:])
+			ctx.siblingKnown = true
+		}
+		render(s, ctx, ch.value)
+		ctx.siblingKnown = false
+		if s.err != nil {
+			return
+		}
+	}
+
+	if block && n.nodeName != "" {
+		ctx.depth--
+		ctx.newline(s)
+	}
+}
+
+// isBlockChildren 判断子节点是否都是块级元素，只有这时才能在它们之间添加换行
+func isBlockChildren(children []child) bool {
+	hasElement := false
+	for _, c := range children {
+		switch {
+		case c.name == "":
+		case c.name == "#text", c.name == "#other", inlineElements[c.name]:
+			return false
+		default:
+			hasElement = true
+		}
+	}
+	return hasElement
+}
+
+// nextSibling 返回下一个会被渲染的兄弟节点的名字，没有时返回空
+func nextSibling(children []child) string {
+	for _, c := range children {
+		if c.name != "" {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// prettyPrint 判断是否需要缩进，minify 优先于 pretty
+func (ctx *RenderCtx) prettyPrint() bool {
+	return ctx.pretty && !ctx.minify
+}
+
+func (ctx *RenderCtx) newline(s *renderWriter) {
+	s.WriteString("\n")
+	for i := 0; i < ctx.depth; i++ {
+		s.WriteString(prettyIndent)
+	}
+}
+
+var whitespaces = regexp.MustCompile(`\s+`)
+
+// collapseWhitespace 在 minify 模式中将连续的空白字符合并为一个空格
+func (ctx *RenderCtx) collapseWhitespace(s string) string {
+	if !ctx.minify || ctx.preserve != 0 {
+		return s
+	}
+	return whitespaces.ReplaceAllString(s, " ")
+}
+
+// renderRawText 渲染 <script>、<style> 等 raw text 元素的子节点，minify 模式中会压缩其中的 js 与 css
+func renderRawText(s *renderWriter, ctx *RenderCtx, n *vnode, children interface{}, escape func(string) string) {
+	prev := ctx.rawText
+	defer func() {
+		ctx.rawText = prev
+	}()
+
+	if !ctx.minify {
+		ctx.rawText = escape
+		render(s, ctx, children)
+		return
+	}
+
+	var b strings.Builder
+	ctx.rawText = func(s string) string { return s }
+	w := &renderWriter{w: &b}
+	render(w, ctx, children)
+	if w.err != nil {
+		s.err = w.err
+		return
+	}
+	s.WriteString(escape(minifyRawText(n, b.String())))
+}
+
+// minifyRawText 压缩 <script>、<style> 中的代码，无法压缩（如语法错误、未知的 script 类型）时原样返回。
+func minifyRawText(n *vnode, code string) string {
+	var loader api.Loader
+	switch n.nodeName {
+	case "style":
+		loader = api.LoaderCSS
+	case "script":
+		t, _ := n.attr("type")
+		typ, _ := t.(string)
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		switch {
+		case typ == "", typ == "module", typ == "text/javascript", typ == "application/javascript":
+			loader = api.LoaderJS
+		case jsonScriptTypes[typ]:
+			var b bytes.Buffer
+			if err := json.Compact(&b, []byte(code)); err != nil {
+				return code
+			}
+			return b.String()
+		default:
+			return code
+		}
+	default:
+		return code
+	}
+
+	r := api.Transform(code, api.TransformOptions{
+		Loader:            loader,
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		MinifySyntax:      true,
+	})
+	if len(r.Errors) != 0 {
+		return code
+	}
+	return strings.TrimSpace(string(r.Code))
+}
+
+// minifyAttributes 省略属性值的引号，空值只保留属性名。
+// attrs 由 renderAttributes 渲染，值都已经被转义，所以不会包含引号。
+func minifyAttributes(attrs string) string {
+	var b strings.Builder
+	for len(attrs) > 0 {
+		// ` name="value"` 或者 ` name`
+		attrs = attrs[1:]
+		end := strings.IndexAny(attrs, ` =`)
+		if end == -1 {
+			end = len(attrs)
+		}
+		b.WriteString(" ")
+		b.WriteString(attrs[:end])
+		attrs = attrs[end:]
+		if !strings.HasPrefix(attrs, `="`) {
+			continue
+		}
+
+		attrs = attrs[2:]
+		end = strings.IndexByte(attrs, '"')
+		if end == -1 {
+			end = len(attrs)
+		}
+		value := attrs[:end]
+		attrs = attrs[end+1:]
+		switch {
+		case value == "":
+		case strings.ContainsAny(value, " \t\n\f\r\"'=<>`"):
+			b.WriteString(`="`)
+			b.WriteString(value)
+			b.WriteString(`"`)
+		default:
+			b.WriteString("=")
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// pClosers 是可以省略 </p> 的下一个兄弟元素
+var pClosers = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true, "main": true,
+	"menu": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// omitEndTag 判断是否可以省略结束标签，next 是下一个兄弟节点的名字，为空表示是父元素的最后一个子节点。
+//
+// https://html.spec.whatwg.org/multipage/syntax.html#optional-tags
+func omitEndTag(name, next, parent string) bool {
+	last := next == ""
+	switch name {
+	case "li":
+		return last || next == "li"
+	case "dt":
+		return next == "dt" || next == "dd"
+	case "dd":
+		return last || next == "dd" || next == "dt"
+	case "p":
+		if last {
+			switch parent {
+			case "", "a", "audio", "del", "ins", "map", "noscript", "video":
+				return false
+			}
+			return true
+		}
+		return pClosers[next]
+	case "rt", "rp":
+		return last || next == "rt" || next == "rp"
+	case "optgroup":
+		return last || next == "optgroup"
+	case "option":
+		return last || next == "option" || next == "optgroup"
+	case "thead":
+		return next == "tbody" || next == "tfoot"
+	case "tbody":
+		return last || next == "tbody" || next == "tfoot"
+	case "tfoot":
+		return last
+	case "tr":
+		return last || next == "tr"
+	case "td", "th":
+		return last || next == "td" || next == "th"
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var formatTestCode = []byte(`
+export default () => <html lang="en">
+	<head>
+		<meta charSet="UTF-8"/>
+		<title>Title</title>
+		<style>{"body  >  p { color : red ; }"}</style>
+	</head>
+	<body>
+		<div className="a  b" hidden>
+			<p>Hello <b>world</b>{"  !  "}</p>
+			<ul>
+				<li><a href="/a">a</a></li>
+				<li>b</li>
+			</ul>
+			<pre>{"  a\n  b  "}</pre>
+			<table><tbody><tr><td>1</td><td>2</td></tr></tbody></table>
+			<p>end</p>
+		</div>
+		<script>{"const message = 'hi';\nconsole.log(message)"}</script>
+	</body>
+</html>
+`)
+
+func TestPretty(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode(formatTestCode, nil, WithPretty(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charSet="UTF-8"/>
+    <title>Title</title>
+    <style>body  >  p { color : red ; }</style>
+  </head>
+  <body>
+    <div class="a b" hidden="">
+      <p>Hello <b>world</b>  !  </p>
+      <ul>
+        <li><a href="/a">a</a></li>
+        <li>b</li>
+      </ul>
+      <pre>  a
+  b  </pre>
+      <table>
+        <tbody>
+          <tr>
+            <td>1</td>
+            <td>2</td>
+          </tr>
+        </tbody>
+      </table>
+      <p>end</p>
+    </div>
+    <script>const message = 'hi';
+console.log(message)</script>
+  </body>
+</html>`, s)
+}
+
+func TestMinify(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode(formatTestCode, nil, WithMinify(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<!DOCTYPE html><html lang=en><head><meta charSet=UTF-8><title>Title</title><style>body>p{color:red}</style></head>`+
+		`<body><div class="a b" hidden><p>Hello <b>world</b> ! <ul><li><a href=/a>a</a><li>b</ul><pre>  a
+  b  </pre><table><tbody><tr><td>1<td>2</table><p>end</div>`+
+		`<script>const message="hi";console.log(message);</script></body></html>`, s)
+
+	// 不能省略结束标签
+	s, _, err = j.RenderCode([]byte(`export default () => <div><a href="#"><p>a</p></a><p>b</p>{"c"}<svg><circle r={1}/></svg></div>`), nil, WithMinify(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div><a href=#><p>a</p></a><p>b</p>c<svg><circle r=1 /></svg></div>`, s)
+}
+
+func TestMinifyAttributes(t *testing.T) {
+	assert.Equal(t, ` a=1 b c="x y" d=&#34; e=/`, minifyAttributes(` a="1" b="" c="x y" d="&#34;" e="/"`))
+	assert.Equal(t, ` disabled`, minifyAttributes(` disabled`))
+}
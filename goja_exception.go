@@ -1,71 +1,271 @@
 package gojsx
 
 import (
+	"fmt"
 	"github.com/dop251/goja"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// Exception 是 js 代码抛出的错误
 type Exception struct {
-	Text   string
+	// Name 是错误的类型，如 TypeError、GoError，抛出的不是 Error 对象（如 throw "x"）时为空
+	Name    string
+	Message string
+	// Cause 是错误的 cause 属性，如 new Error("x", {cause: e})
+	Cause  error
+	Frames []Frame
+	// ComponentStack 是抛出错误时正在调用的组件，如 [Index Layout Widget]
+	ComponentStack []string
+	// CodeFrame 是出错位置附近的源码，只有通过 Jsx 执行的代码才会有
+	CodeFrame string
+
+	// Deprecated: Text 是错误信息（如 "TypeError: x is not a function"），使用 Name 与 Message
+	Text string
+	// Deprecated: Stacks 是调用栈中的每一帧（如 "at Index (test/Index.jsx:14:23)"），使用 Frames
 	Stacks []string
+
+	// goErr 是原生模块（如 RegisterComponent 注册的组件）抛出的 go error
+	goErr     error
+	exception *goja.Exception
+	// reason 是抛出或者 Promise reject 的值，用于 <ErrorBoundary> 的 fallback，见 renderBoundary
+	reason goja.Value
+}
+
+// Frame 是调用栈中的一帧
+type Frame struct {
+	// Function 是函数名，在模块顶层时为空
+	Function string
+	File     string
+	// Line 从 1 开始，Column 从 0 开始
+	Line   int
+	Column int
+	// Native 表示 go 实现的函数，没有位置
+	Native bool
+}
+
+func (f Frame) String() string {
+	var loc string
+	if f.Native {
+		loc = "native"
+	} else {
+		loc = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	}
+	if f.Function == "" {
+		return loc
+	}
+	return f.Function + " (" + loc + ")"
+}
+
+// title 返回错误的类型与信息，如 "TypeError: x is not a function"
+func (e *Exception) title() string {
+	if e.Name != "" && e.Message != "" {
+		return e.Name + ": " + e.Message
+	}
+	return e.Name + e.Message
+}
+
+// fillDeprecated 根据 Name、Message 与 Frames 填充已废弃的 Text 与 Stacks
+func (e *Exception) fillDeprecated() *Exception {
+	e.Text = e.title()
+	e.Stacks = make([]string, len(e.Frames))
+	for i, f := range e.Frames {
+		e.Stacks[i] = "at " + f.String()
+	}
+	return e
 }
 
 func (e *Exception) Error() string {
 	var b strings.Builder
-	b.WriteString(e.Text)
-	for _, s := range e.Stacks {
+	b.WriteString(e.title())
+	for _, f := range e.Frames {
 		// skip golang require function Stack
-		if strings.HasSuffix(s, "(native)") {
+		if f.Native {
 			continue
 		}
-
-		// rm pt
-		if strings.HasSuffix(s, "))") {
-			i := strings.LastIndex(s, "(")
-			s = s[:i] + s[len(s)-1:]
-		} else if strings.HasSuffix(s, ")") {
-			i := strings.LastIndex(s, "(")
-			s = s[:i]
-		}
-
-		b.WriteString("\n\t")
-		b.WriteString(strings.TrimSpace(s))
+		b.WriteString("\n\tat ")
+		b.WriteString(f.String())
+	}
+	if len(e.ComponentStack) != 0 {
+		b.WriteString("\n\tcomponent stack: ")
+		b.WriteString(strings.Join(e.ComponentStack, " > "))
+	}
+	if e.Cause != nil {
+		b.WriteString("\n\tcaused by: ")
+		b.WriteString(strings.ReplaceAll(e.Cause.Error(), "\n", "\n\t"))
+	}
+	if e.CodeFrame != "" {
+		b.WriteString("\n\n")
+		b.WriteString(e.CodeFrame)
 	}
 
 	return b.String()
 }
 
-func parseException(s string) error {
-	ss := strings.Split(s, "\n")
-	if len(ss) == 0 {
-		return nil
+// Unwrap 返回原生模块抛出的 go error，没有则返回 Cause
+func (e *Exception) Unwrap() error {
+	if e.goErr != nil {
+		return e.goErr
+	}
+	return e.Cause
+}
+
+// As 使 errors.As 可以得到原始的 *goja.Exception
+func (e *Exception) As(target interface{}) bool {
+	if t, ok := target.(**goja.Exception); ok && e.exception != nil {
+		*t = e.exception
+		return true
 	}
-	var errMsg strings.Builder
-	stack := make([]string, 0)
-	for _, s := range ss {
+	return false
+}
+
+// errorNameRegexp 匹配错误信息中的类型，如 "TypeError: x is not a function"
+var errorNameRegexp = regexp.MustCompile(`^([A-Za-z_$][\w$]*): `)
+
+// parseException 解析 js 错误的 stack，如 "Error: x\n\tat Index (test/Index.jsx:14:23(64))"
+func parseException(s string) *Exception {
+	var msg strings.Builder
+	e := &Exception{}
+	for _, s := range strings.Split(s, "\n") {
 		if s == "" {
 			continue
-		} else if strings.HasPrefix(strings.TrimSpace(s), "at") {
-			stack = append(stack, s)
+		} else if strings.HasPrefix(strings.TrimSpace(s), "at ") {
+			e.Frames = append(e.Frames, parseFrame(s))
 		} else {
-			if errMsg.Len() != 0 {
-				errMsg.WriteByte('\n')
+			if msg.Len() != 0 {
+				msg.WriteByte('\n')
 			}
-			errMsg.WriteString(s)
+			msg.WriteString(s)
 		}
 	}
-	return &Exception{
-		Text:   errMsg.String(),
-		Stacks: stack,
+	e.Message = msg.String()
+	if m := errorNameRegexp.FindStringSubmatch(e.Message); m != nil {
+		e.Name = m[1]
+		e.Message = e.Message[len(m[0]):]
 	}
+	return e.fillDeprecated()
+}
+
+// frameLocationRegexp 匹配调用栈中的位置，如 "test/Index.jsx:14:23(64)"，括号中是 pc
+var frameLocationRegexp = regexp.MustCompile(`^(.*):(\d+):(\d+)(?:\(\d+\))?$`)
+
+// parseFrame 解析调用栈中的一行，如 "at Index (test/Index.jsx:14:23(64))"、"at test/Index.jsx:1:16(55)"、"at require (native)"
+func parseFrame(s string) Frame {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "at ")
+	var f Frame
+	loc := s
+	if i := strings.LastIndex(s, " ("); i != -1 && strings.HasSuffix(s, ")") {
+		f.Function = s[:i]
+		loc = s[i+2 : len(s)-1]
+	}
+	if loc == "native" {
+		f.Native = true
+		return f
+	}
+	if m := frameLocationRegexp.FindStringSubmatch(loc); m != nil {
+		f.File = m[1]
+		f.Line, _ = strconv.Atoi(m[2])
+		f.Column, _ = strconv.Atoi(m[3])
+	} else {
+		f.File = loc
+	}
+	return f
+}
+
+// newException 使用错误的属性创建 Exception，fields 见 errorFields
+func newException(fields map[string]interface{}) *Exception {
+	e := &Exception{}
+	if stack, ok := fields["stack"].(string); ok {
+		e = parseException(stack)
+	}
+	if name, ok := fields["name"].(string); ok {
+		e.Name = name
+	}
+	if msg, ok := fields["message"].(string); ok {
+		e.Message = msg
+	}
+	e.ComponentStack = componentStack(fields["componentStack"])
+	e.goErr, _ = fields["value"].(error)
+	switch c := fields["cause"].(type) {
+	case nil:
+	case map[string]interface{}:
+		e.Cause = newException(c)
+	case error:
+		e.Cause = c
+	default:
+		e.Cause = newException(map[string]interface{}{"message": fmt.Sprint(c)})
+	}
+	return e.fillDeprecated()
+}
+
+// maxCauseDepth 限制读取 cause 的层数，cause 可能是循环引用
+const maxCauseDepth = 8
+
+// errorFields 读取 js 错误的属性，与 jsx-runtime.js 中的 describeError 相同：
+// name、message、stack、componentStack、cause，以及 GoError 包装的 go error（value）
+func errorFields(v goja.Value) map[string]interface{} {
+	return errorFieldsDepth(v, 0)
+}
+
+func errorFieldsDepth(v goja.Value, depth int) map[string]interface{} {
+	o, ok := v.(*goja.Object)
+	if !ok {
+		if v == nil {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"message": v.String()}
+	}
+
+	fields := map[string]interface{}{}
+	for _, k := range []string{"name", "message", "stack"} {
+		if p := o.Get(k); p != nil && !goja.IsUndefined(p) && !goja.IsNull(p) {
+			fields[k] = p.String()
+		}
+	}
+	if _, ok := fields["message"]; !ok {
+		fields["message"] = o.String()
+	}
+	if p := o.Get("componentStack"); p != nil {
+		fields["componentStack"] = gojaExport(p)
+	}
+	if p := o.Get("value"); p != nil {
+		if err, ok := p.Export().(error); ok {
+			fields["value"] = err
+		}
+	}
+	if p := o.Get("cause"); p != nil && !goja.IsUndefined(p) && depth < maxCauseDepth {
+		fields["cause"] = errorFieldsDepth(p, depth+1)
+	}
+	return fields
 }
 
 // PrettifyException make goja exceptions look more prettify.
 func PrettifyException(err error) error {
-	// return err
 	if ex, ok := err.(*goja.Exception); ok {
-		return parseException(ex.String())
+		fields := errorFields(ex.Value())
+		e := newException(fields)
+		if _, ok := fields["stack"]; !ok {
+			// 抛出的不是 Error 对象，使用抛出位置的调用栈
+			e.Frames = parseException(ex.String()).Frames
+			e.fillDeprecated()
+		}
+		e.exception = ex
+		e.reason = ex.Value()
+		return e
 	}
 
 	return err
 }
+
+// componentStack 读取 jsx-runtime 在错误对象上设置的 componentStack
+func componentStack(i interface{}) []string {
+	var names []string
+	forEachItem(i, func(i interface{}) bool {
+		if s, ok := i.(string); ok {
+			names = append(names, s)
+		}
+		return true
+	})
+	return names
+}
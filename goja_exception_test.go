@@ -1,6 +1,8 @@
 package gojsx
 
 import (
+	"errors"
+	"github.com/dop251/goja"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -42,4 +44,115 @@ func TestEx(t *testing.T) {
 	for _, c := range cases {
 		assert.Equal(t, c.Out, parseException(c.In).Error())
 	}
+
+	ex := parseException(cases[1].In)
+	assert.Equal(t, "ReferenceError", ex.Name)
+	assert.Equal(t, "i is not defined", ex.Message)
+	assert.Equal(t, []Frame{
+		{Function: "Index", File: "test/Index.jsx", Line: 14, Column: 23},
+		{File: "root.js", Line: 1, Column: 32},
+	}, ex.Frames)
+
+	ex = parseException(cases[0].In)
+	assert.Equal(t, Frame{Function: "github.com/zbysir/gojsx/internal/pkg/goja_nodejs/require.(*RequireModule).require-fm", Native: true}, ex.Frames[0])
+}
+
+func TestExceptionFields(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = j.ExecCode([]byte(`
+const cause = new TypeError("bad input")
+const e = new Error("render failed")
+e.cause = cause
+throw e`))
+	var ex *Exception
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, "Error", ex.Name)
+		assert.Equal(t, "render failed", ex.Message)
+		assert.Equal(t, Frame{File: "index.jsx", Line: 3, Column: 10}, ex.Frames[0])
+		var cause *Exception
+		if assert.ErrorAs(t, ex.Cause, &cause) {
+			assert.Equal(t, "TypeError", cause.Name)
+			assert.Equal(t, "bad input", cause.Message)
+			assert.Equal(t, 2, cause.Frames[0].Line)
+		}
+		assert.Contains(t, ex.Error(), "\n\tcaused by: TypeError: bad input\n\t\tat index.jsx:2:14")
+
+		// 兼容已废弃的字段
+		assert.Equal(t, "Error: render failed", ex.Text)
+		assert.Equal(t, "at index.jsx:3:10", ex.Stacks[0])
+		assert.Equal(t, len(ex.Frames), len(ex.Stacks))
+	}
+	var gex *goja.Exception
+	if assert.ErrorAs(t, err, &gex) {
+		assert.Equal(t, "render failed", gex.Value().(*goja.Object).Get("message").String())
+	}
+
+	// 抛出的不是 Error 对象
+	_, err = j.ExecCode([]byte(`throw "oops"`))
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, "", ex.Name)
+		assert.Equal(t, "oops", ex.Message)
+		assert.Equal(t, "index.jsx", ex.Frames[0].File)
+
+		// 已废弃的字段同样使用抛出位置的调用栈
+		assert.Equal(t, "oops", ex.Text)
+		if assert.Equal(t, len(ex.Frames), len(ex.Stacks)) {
+			assert.Equal(t, "at "+ex.Frames[0].String(), ex.Stacks[0])
+		}
+	}
+
+	// 原生模块抛出的 go error
+	errNative := errors.New("native failure")
+	j.RegisterModule("@test/native", map[string]interface{}{
+		"run": func() error { return errNative },
+	})
+	_, err = j.ExecCode([]byte(`import {run} from "@test/native"; run()`))
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, "GoError", ex.Name)
+		assert.Equal(t, "native failure", ex.Message)
+	}
+	assert.ErrorIs(t, err, errNative)
+}
+
+func TestComponentStack(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = j.RenderCode([]byte(`
+function Widget() {
+	return JSON.parse("{")
+}
+function Sidebar() {
+	return <aside><Widget/></aside>
+}
+Sidebar.displayName = "Layout.Sidebar"
+function Index() {
+	return <main><Sidebar/></main>
+}
+export default () => <Index/>`), nil)
+	var ex *Exception
+	if assert.ErrorAs(t, err, &ex) {
+		assert.Equal(t, []string{"Index", "Layout.Sidebar", "Widget"}, ex.ComponentStack)
+		assert.Contains(t, ex.Error(), "\n\tcomponent stack: Index > Layout.Sidebar > Widget")
+	}
+
+	// 被 <ErrorBoundary> 捕获的错误
+	_, ctx, err := j.RenderCode([]byte(`
+import {ErrorBoundary} from "gojsx"
+const Widget = () => { throw new Error("x") }
+const Sidebar = () => <ErrorBoundary fallback=""><Widget/></ErrorBoundary>
+const Index = () => <><Sidebar/></>
+export default () => <Index/>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Equal(t, 1, len(ctx.Errors)) {
+		assert.Equal(t, []string{"Index", "Sidebar", "Widget"}, ctx.Errors[0].(*Exception).ComponentStack)
+	}
 }
@@ -0,0 +1,59 @@
+package gojsx
+
+import (
+	"context"
+	"errors"
+	"github.com/dop251/goja"
+)
+
+// TimeoutError is returned when the execution is interrupted because the context is canceled or its deadline is exceeded.
+type TimeoutError struct {
+	// Err is the error returned by context.Err(), one of context.Canceled and context.DeadlineExceeded.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return "execution interrupted: " + e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// interruptOnDone 在 ctx 结束时中断 vm 的执行，返回的 stop 函数需要在执行结束后调用，stop 返回后不会再有 Interrupt 发生。
+func interruptOnDone(ctx context.Context, vm *goja.Runtime) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			vm.Interrupt(&TimeoutError{Err: ctx.Err()})
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// asTimeoutError 将被 Interrupt 中断的错误转换为 *TimeoutError
+func asTimeoutError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		return te
+	}
+	if ctx.Err() != nil {
+		return &TimeoutError{Err: ctx.Err()}
+	}
+	return err
+}
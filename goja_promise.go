@@ -0,0 +1,98 @@
+package gojsx
+
+import (
+	"errors"
+	"github.com/dop251/goja"
+)
+
+// ErrPromisePending is returned when a promise in VDom is never settled.
+// goja has no event loop, so only the promises settled by microtasks (e.g. async/await) can be resolved.
+var ErrPromisePending = errors.New("promise is still pending after running all jobs")
+
+// resolvePromise 执行 job 队列直到 Promise 完成，返回 Promise 的结果。vm 为 nil 时不会执行 job 队列。
+func resolvePromise(vm *goja.Runtime, p *goja.Promise) (goja.Value, error) {
+	if p.State() == goja.PromiseStatePending && vm != nil {
+		// goja 在每一次执行结束时都会执行 job 队列，所以执行一个空程序即可驱动队列。
+		_, err := vm.RunString("")
+		if err != nil {
+			return nil, PrettifyException(err)
+		}
+	}
+
+	switch p.State() {
+	case goja.PromiseStateFulfilled:
+		return p.Result(), nil
+	case goja.PromiseStateRejected:
+		return nil, promiseRejectionError(p.Result())
+	default:
+		return nil, ErrPromisePending
+	}
+}
+
+// awaitValue 在 c 是 Promise 时返回它的结果，否则返回 c，用于在渲染之前遍历 VDom（如 walkHead）
+func awaitValue(vm *goja.Runtime, c interface{}) (interface{}, error) {
+	for {
+		var p *goja.Promise
+		switch t := c.(type) {
+		case *goja.Promise:
+			p = t
+		case *goja.Object:
+			if isPromise(t) {
+				p, _ = t.Export().(*goja.Promise)
+			}
+		}
+		if p == nil {
+			return c, nil
+		}
+		v, err := resolvePromise(vm, p)
+		if err != nil {
+			return nil, err
+		}
+		c = v
+	}
+}
+
+// promiseRejectionError 将 reject 的值转为 error，如果是 Error 对象则保留调用栈
+func promiseRejectionError(reason goja.Value) error {
+	if reason == nil {
+		return newException(map[string]interface{}{"message": "promise rejected"})
+	}
+	e := newException(errorFields(reason))
+	e.reason = reason
+	return e
+}
+
+// resolvePromises 将 VDom 树中的 Promise（如 async 组件的返回值）替换为它的结果
+func resolvePromises(vm *goja.Runtime, i interface{}) (interface{}, error) {
+	switch t := i.(type) {
+	case *goja.Promise:
+		v, err := resolvePromise(vm, t)
+		if err != nil {
+			return nil, err
+		}
+		return resolvePromises(vm, v.Export())
+	case map[string]interface{}:
+		for k, v := range t {
+			r, err := resolvePromises(vm, v)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = r
+		}
+	case VDom:
+		_, err := resolvePromises(vm, map[string]interface{}(t))
+		if err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		for k, v := range t {
+			r, err := resolvePromises(vm, v)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = r
+		}
+	}
+
+	return i, nil
+}
@@ -0,0 +1,193 @@
+package gojsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// headTag 是 <Head> 中的一个标签
+type headTag struct {
+	// key 用于去重，为空则不去重
+	key  string
+	node interface{}
+}
+
+// headTagKey 计算 <Head> 中标签的去重 key，相同 key 的标签只会保留最后一个。
+func headTagKey(n *vnode) string {
+	if n.key != nil {
+		return fmt.Sprintf("key:%v", n.key)
+	}
+
+	switch n.nodeName {
+	case "title", "base":
+		return n.nodeName
+	case "meta":
+		if _, ok := n.attr("charSet"); ok {
+			return "meta:charset"
+		}
+		for _, a := range []string{"name", "property", "httpEquiv", "http-equiv", "itemProp"} {
+			if v, ok := n.attr(a); ok {
+				return fmt.Sprintf("meta:%s:%v", strings.ToLower(a), v)
+			}
+		}
+	case "link":
+		if rel, _ := n.attr("rel"); rel == "canonical" {
+			return "link:canonical"
+		}
+	}
+
+	return ""
+}
+
+// flattenHeadTags 展开 Fragment、数组与 Promise，返回其中的标签，Promise reject 时返回错误
+func flattenHeadTags(ctx *RenderCtx, tags []headTag, c interface{}) ([]headTag, error) {
+	c, err := awaitValue(ctx.vm, c)
+	if err != nil {
+		return nil, err
+	}
+	if isNullish(c) {
+		return tags, nil
+	}
+	if _, ok := c.(bool); ok {
+		return tags, nil
+	}
+	if forEachItem(c, func(c interface{}) bool {
+		tags, err = flattenHeadTags(ctx, tags, c)
+		return err == nil
+	}) {
+		return tags, err
+	}
+
+	n, ok := toVNode(c)
+	if !ok {
+		return append(tags, headTag{node: c}), nil
+	}
+	if n.head {
+		// 在 walkHead 中收集
+		return tags, nil
+	}
+	if n.nodeName == "" && !n.isHTML {
+		return flattenHeadTags(ctx, tags, n.children)
+	}
+	return append(tags, headTag{key: headTagKey(&n), node: c}), nil
+}
+
+// walkHead 按文档顺序找到所有 <Head> 中的标签，包括 async 组件返回的 <Head>，Promise reject 时返回错误
+func walkHead(ctx *RenderCtx, tags []headTag, c interface{}) ([]headTag, error) {
+	c, err := awaitValue(ctx.vm, c)
+	if err != nil {
+		return nil, err
+	}
+	if forEachItem(c, func(c interface{}) bool {
+		tags, err = walkHead(ctx, tags, c)
+		return err == nil
+	}) {
+		return tags, err
+	}
+
+	n, ok := toVNode(c)
+	if !ok {
+		return tags, nil
+	}
+	if n.head {
+		if tags, err = flattenHeadTags(ctx, tags, n.children); err != nil {
+			return nil, err
+		}
+	}
+	if n.boundary {
+		// reject 时渲染 fallback，忽略其中的 <Head>
+		if t, err := walkHead(ctx, tags, n.children); err == nil {
+			return t, nil
+		}
+		return tags, nil
+	}
+	return walkHead(ctx, tags, n.children)
+}
+
+// mergeHeadTags 去重，相同 key 的标签保留第一次出现的位置和最后一次出现的值。
+func mergeHeadTags(tags []headTag) []headTag {
+	index := map[string]int{}
+	merged := make([]headTag, 0, len(tags))
+	for _, t := range tags {
+		if t.key != "" {
+			if i, ok := index[t.key]; ok {
+				merged[i] = t
+				continue
+			}
+			index[t.key] = len(merged)
+		}
+		merged = append(merged, t)
+	}
+	return merged
+}
+
+// renderHead 渲染 <head> 的子节点，以及整个文档中 <Head> 里的标签
+func renderHead(s *renderWriter, ctx *RenderCtx, children interface{}) {
+	tags, err := flattenHeadTags(ctx, nil, children)
+	if err != nil {
+		s.err = err
+		return
+	}
+	docTags, err := walkHead(ctx, nil, ctx.root)
+	if err != nil {
+		s.err = err
+		return
+	}
+	ctx.headTags = mergeHeadTags(append(tags, docTags...))
+	ctx.headRendered = true
+
+	ctx.Head = ctx.Head[:0]
+	if ctx.prettyPrint() {
+		ctx.depth++
+	}
+	for _, t := range ctx.headTags {
+		h, err := renderHeadTag(ctx, t)
+		if err != nil {
+			s.err = err
+			return
+		}
+		ctx.Head = append(ctx.Head, h)
+		if ctx.prettyPrint() {
+			ctx.newline(s)
+		}
+		s.WriteString(h)
+	}
+	if ctx.prettyPrint() {
+		ctx.depth--
+		if len(ctx.headTags) != 0 {
+			ctx.newline(s)
+		}
+	}
+}
+
+// renderHeadTag 将 t 渲染为字符串，用于 RenderCtx.Head
+func renderHeadTag(ctx *RenderCtx, t headTag) (string, error) {
+	var b strings.Builder
+	w := &renderWriter{w: &b}
+	render(w, ctx, t.node)
+	return b.String(), w.err
+}
+
+// collectHead 处理不在 <head> 中渲染的 <Head>（如只渲染了一个片段），将标签收集到 RenderCtx.Head。
+func collectHead(s *renderWriter, ctx *RenderCtx, n *vnode) {
+	if ctx.headRendered {
+		return
+	}
+
+	tags, err := flattenHeadTags(ctx, ctx.headTags, n.children)
+	if err != nil {
+		s.err = err
+		return
+	}
+	ctx.headTags = mergeHeadTags(tags)
+
+	ctx.Head = ctx.Head[:0]
+	for _, t := range ctx.headTags {
+		h, err := renderHeadTag(ctx, t)
+		if err != nil {
+			s.err = err
+			return
+		}
+		ctx.Head = append(ctx.Head, h)
+	}
+}
@@ -0,0 +1,106 @@
+package gojsx
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// hydrateEventName 将 hydrate 属性名转为 DOM 事件名，如 hydrate-onclick、hydrateOnClick => click。
+// 不是事件的属性（如 hydrate-a）返回 false。
+func hydrateEventName(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "hydrate")
+	name = strings.TrimPrefix(name, "-")
+	if len(name) <= 2 || strings.ToLower(name[:2]) != "on" {
+		return "", false
+	}
+
+	event := strings.ToLower(strings.TrimPrefix(name[2:], "-"))
+	// React 中的 onDoubleClick 对应 dblclick 事件
+	if event == "doubleclick" {
+		event = "dblclick"
+	}
+	return event, true
+}
+
+// hydrateDataName 返回非事件属性在 element.hydrate 对象上的字段名，如 hydrate-a => a
+func hydrateDataName(name string) string {
+	name = strings.TrimPrefix(name, "hydrate")
+	name = strings.TrimPrefix(name, "-")
+	return name
+}
+
+// escapeInlineScript 避免代码中的 </script> 提前结束 script 标签
+func escapeInlineScript(code string) string {
+	return escapeRawText("script", code)
+}
+
+func jsonString(s string) string {
+	bs, _ := json.Marshal(s)
+	return string(bs)
+}
+
+// HydrationScript 生成在浏览器中绑定 Hydrate 的 js 代码，不包含 <script> 标签。
+//
+// hydrate 属性的值是一段 js 表达式：
+//   - 事件属性（如 hydrate-onclick、hydrateOnClick）的值需要是一个函数，会通过 addEventListener 绑定到元素上。
+//   - 其他属性（如 hydrate-a）的值会被赋值给元素的 hydrate 对象，如 element.hydrate.a。
+//
+// 没有收集到 hydrate 属性时返回空字符串。
+func (ctx *RenderCtx) HydrationScript() string {
+	if len(ctx.Hydrate) == 0 {
+		return ""
+	}
+
+	var s strings.Builder
+	s.WriteString("(function(){var h={")
+	for i, id := range ctx.hydrateIds() {
+		if i != 0 {
+			s.WriteString(",")
+		}
+		s.WriteString(jsonString(id))
+		s.WriteString(":function(el){")
+
+		props := ctx.Hydrate[id]
+		var data []string
+		sortMap(toInterfaceMap(props), func(k string, _ interface{}) {
+			code := props[k]
+			if event, ok := hydrateEventName(k); ok {
+				s.WriteString("el.addEventListener(")
+				s.WriteString(jsonString(event))
+				s.WriteString(",(")
+				s.WriteString(code)
+				s.WriteString("));")
+			} else {
+				data = append(data, jsonString(hydrateDataName(k))+":("+code+")")
+			}
+		})
+		if len(data) != 0 {
+			s.WriteString("el.hydrate={")
+			s.WriteString(strings.Join(data, ","))
+			s.WriteString("};")
+		}
+		s.WriteString("}")
+	}
+	s.WriteString(`};for(var id in h){var el=document.querySelector('[data-hydrate="'+id+'"]');if(el){h[id](el)}}})();`)
+
+	return escapeInlineScript(s.String())
+}
+
+// hydrateIds 按添加顺序返回所有 hydrate id
+func (ctx *RenderCtx) hydrateIds() []string {
+	ids := make([]string, 0, len(ctx.Hydrate))
+	for _, id := range ctx.hydrateOrder {
+		if _, ok := ctx.Hydrate[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
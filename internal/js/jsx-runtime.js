@@ -1,15 +1,24 @@
-export function jsx(nodeName, attributes) {
+export function jsx(nodeName, attributes, key) {
     if (typeof nodeName === 'string') {
+        if (key !== undefined) {
+            return {
+                nodeName, attributes, key,
+            }
+        }
         return {
             nodeName, attributes,
         }
     } else {
-        return nodeName(attributes)
+        const client = clientDirective(nodeName, attributes)
+        if (client) {
+            return island(nodeName, attributes, client)
+        }
+        return callComponent(nodeName, attributes)
     }
 }
 
-export function jsxs(nodeName, attributes) {
-    return jsx(nodeName, attributes)
+export function jsxs(nodeName, attributes, key) {
+    return jsx(nodeName, attributes, key)
 }
 
 export function Fragment(args) {
@@ -17,3 +26,141 @@ export function Fragment(args) {
         nodeName: "", attributes: args
     }
 }
+
+// Head hoists its children (e.g. <title>, <meta>, <link rel="canonical">) into the <head> of the document.
+export function Head(props) {
+    return {
+        nodeName: "", attributes: props, __head: true,
+    }
+}
+
+// CDATA renders its text children as a CDATA section in XML mode (see WithXML) and in SVG/MathML.
+export function CDATA(props) {
+    return {
+        nodeName: "", attributes: props, __cdata: true,
+    }
+}
+
+// clientDirective returns the directive of <Counter client:load/>, or the `island` flag exported by the component module.
+function clientDirective(component, attributes) {
+    if (attributes) {
+        for (const k in attributes) {
+            if (k.startsWith("client:")) {
+                return k.slice("client:".length)
+            }
+        }
+    }
+    return component.__gojsxIsland ? component.__gojsxIsland.client : ""
+}
+
+// island renders the component on the server, and records the module and props for hydrating it in the browser.
+function island(component, attributes, client) {
+    const info = component.__gojsxIsland
+    if (!info) {
+        throw new Error(`client:${client} can only be used on a component exported from a module file: ${component.name || "anonymous"}`)
+    }
+
+    const props = {}
+    for (const k in attributes) {
+        if (!k.startsWith("client:")) {
+            props[k] = attributes[k]
+        }
+    }
+    const {children, ...serializable} = props
+
+    return {
+        nodeName: "gojsx-island",
+        attributes: {children: callComponent(component, props)},
+        __island: {src: info.src, export: info.export, client, props: JSON.stringify(serializable)},
+    }
+}
+
+// pendingErrors records the errors thrown by components, gojsx rethrows them after the execution unless they are caught by <ErrorBoundary>.
+// It is a global variable because the runtime may be imported by different paths (react/jsx-runtime and gojsx).
+function pendingErrors() {
+    return globalThis.__gojsxPendingErrors || (globalThis.__gojsxPendingErrors = [])
+}
+
+// callComponent calls the component, if it throws, returns an error node which can be caught by an <ErrorBoundary> ancestor.
+// The children are evaluated before their parents, so the error can not be caught by try/catch in <ErrorBoundary>.
+function callComponent(component, attributes) {
+    const builtin = builtins.has(component)
+    if (!builtin) {
+        componentStack.push(component.displayName || component.name || "Anonymous")
+    }
+    try {
+        return component(attributes)
+    } catch (e) {
+        if (e !== null && typeof e === "object" && !e.componentStack) {
+            e.componentStack = [...componentStack]
+        }
+        const node = {nodeName: "", __error: e}
+        pendingErrors().push(node)
+        return node
+    } finally {
+        if (!builtin) {
+            componentStack.pop()
+        }
+    }
+}
+
+// componentStack is the names of the components being called, e.g. ["Index", "Layout", "Widget"].
+// The children are evaluated before their parents, so it is the chain of components creating each other, not the parents in the tree.
+const componentStack = []
+
+// builtins are not recorded in the component stack
+const builtins = new Set([Fragment, Head, CDATA, ErrorBoundary])
+
+// ErrorBoundary renders the fallback instead of its children if any descendant component throws or rejects (async components).
+// fallback can be a node or a function receiving the error, the caught error is recorded in RenderCtx.Errors.
+export function ErrorBoundary({fallback, children}) {
+    const errors = pendingErrors()
+    const caught = errors.length ? findErrors(children, new Set(errors), []) : []
+    if (!caught.length) {
+        // async components are settled later, gojsx renders the fallback if one of them rejects, see renderBoundary
+        return {nodeName: "", attributes: {children}, __boundary: {fallback}}
+    }
+
+    globalThis.__gojsxPendingErrors = errors.filter(e => !caught.includes(e))
+    const error = caught[0].__error
+    return {
+        nodeName: "",
+        attributes: {children: typeof fallback === "function" ? fallback(error) : fallback},
+        __caught: describeError(error),
+    }
+}
+
+// describeError returns the fields of the error read by gojsx, see errorFields in goja_exception.go
+function describeError(e, depth = 0) {
+    if (e === null || typeof e !== "object") {
+        return {message: String(e)}
+    }
+    const str = (v) => v === undefined || v === null ? undefined : String(v)
+    return {
+        name: str(e.name),
+        message: e.message === undefined || e.message === null ? String(e) : String(e.message),
+        stack: str(e.stack),
+        componentStack: e.componentStack,
+        // GoError wraps the go error in value
+        value: e.value,
+        cause: e.cause !== undefined && depth < 8 ? describeError(e.cause, depth + 1) : undefined,
+    }
+}
+
+// findErrors finds the error nodes in the tree, including the nodes in the attributes, e.g. <Layout sidebar={<Widget/>}/>
+function findErrors(node, errors, found, visited = new Set()) {
+    if (node === null || typeof node !== "object" || visited.has(node)) {
+        return found
+    }
+    visited.add(node)
+    if (errors.has(node)) {
+        found.push(node)
+    } else if (Array.isArray(node)) {
+        node.forEach(n => findErrors(n, errors, found, visited))
+    } else if (node.attributes) {
+        for (const k in node.attributes) {
+            findErrors(node.attributes[k], errors, found, visited)
+        }
+    }
+    return found
+}
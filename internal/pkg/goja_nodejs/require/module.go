@@ -49,13 +49,24 @@ type Registry struct {
 	SrcLoader     SourceLoader
 	globalFolders []string
 	timeTracker   *timetrack.TimeTracker
+	requireHook   RequireHook
 }
 
+// RequireHook is called before a module (except native modules) is resolved, a non-nil error denies the require.
+// resolved is the path of a relative or absolute module, it is empty for bare modules (e.g. "lodash").
+type RequireHook func(specifier, resolved string) error
+
 type RequireModule struct {
 	r            *Registry
 	runtime      *js.Runtime
 	modulesCache *lru.Cache[string, *js.Object]
 	nodeModules  map[string]*js.Object
+	// overlay takes precedence over the native modules of the registry, see SetOverlay
+	overlay map[string]ModuleLoader
+	// loading is the stack of modules being loaded, overlayUsers are the cached modules which import
+	// an overlay module directly or indirectly, they are removed from the cache when the overlay changes.
+	loading      []*js.Object
+	overlayUsers map[*js.Object]bool
 }
 
 func NewRegistry(opts ...Option) *Registry {
@@ -90,6 +101,13 @@ func WithLoader(srcLoader SourceLoader) Option {
 	}
 }
 
+// WithRequireHook sets a RequireHook which can deny the require of modules.
+func WithRequireHook(hook RequireHook) Option {
+	return func(r *Registry) {
+		r.requireHook = hook
+	}
+}
+
 // WithGlobalFolders appends the given paths to the registry's list of
 // global folders to search if the requested module is not found
 // elsewhere.  By default, a registry's global folders list is empty.
@@ -117,6 +135,13 @@ func (r *Registry) Enable(runtime *js.Runtime) *RequireModule {
 	return rrt
 }
 
+func (r *Registry) checkRequire(specifier, resolved string) error {
+	if r.requireHook == nil {
+		return nil
+	}
+	return r.requireHook(specifier, resolved)
+}
+
 func (r *Registry) RegisterNativeModule(name string, loader ModuleLoader) {
 	r.Lock()
 	defer r.Unlock()
@@ -200,7 +225,11 @@ func (r *Registry) getCompiledSource(p string) (*js.Program, error) {
 func (r *RequireModule) require(call js.FunctionCall) js.Value {
 	ret, err := r.Require(call.Argument(0).String())
 	if err != nil {
-		if _, ok := err.(*js.Exception); !ok {
+		switch err.(type) {
+		case *js.Exception:
+		// keep uncatchable errors (e.g. vm.Interrupt) uncatchable
+		case *js.InterruptedError, *js.StackOverflowError:
+		default:
 			panic(r.runtime.NewGoError(err))
 		}
 		panic(err)
@@ -222,8 +251,59 @@ func (r *RequireModule) Require(p string) (ret js.Value, err error) {
 	return
 }
 
+// SetOverlay replaces the native modules which take precedence over the registered ones for this runtime,
+// the cached modules of the previous overlay and the cached modules importing them are removed.
+// SetOverlay(nil) removes the overlay.
+func (r *RequireModule) SetOverlay(modules map[string]ModuleLoader) {
+	for name := range r.overlay {
+		r.modulesCache.Remove(name)
+	}
+	r.removeOverlayUsers()
+	r.overlay = nil
+	for name, ldr := range modules {
+		if r.overlay == nil {
+			r.overlay = make(map[string]ModuleLoader, len(modules))
+		}
+		name = filepathClean(name)
+		r.overlay[name] = ldr
+		r.modulesCache.Remove(name)
+	}
+}
+
+// trackOverlay marks the modules being loaded as overlay users if module is an overlay module or an overlay user.
+func (r *RequireModule) trackOverlay(module *js.Object, isOverlay bool) {
+	if len(r.loading) == 0 || !isOverlay && !r.overlayUsers[module] {
+		return
+	}
+	if r.overlayUsers == nil {
+		r.overlayUsers = make(map[*js.Object]bool)
+	}
+	for _, m := range r.loading {
+		r.overlayUsers[m] = true
+	}
+}
+
+// removeOverlayUsers removes the overlay users from the cache, a module may be cached by several paths.
+func (r *RequireModule) removeOverlayUsers() {
+	if len(r.overlayUsers) == 0 {
+		return
+	}
+	for _, k := range r.modulesCache.Keys() {
+		if m, ok := r.modulesCache.Peek(k); ok && r.overlayUsers[m] {
+			r.modulesCache.Remove(k)
+		}
+	}
+	for k, m := range r.nodeModules {
+		if r.overlayUsers[m] {
+			delete(r.nodeModules, k)
+		}
+	}
+	r.overlayUsers = nil
+}
+
 func (r *RequireModule) Clean() {
 	r.modulesCache.Purge()
+	r.overlayUsers = nil
 	return
 }
 
@@ -103,6 +103,36 @@ func TestRequireRegistryNativeModule(t *testing.T) {
 	}
 }
 
+func TestRequireOverlay(t *testing.T) {
+	value := func(v string) ModuleLoader {
+		return func(vm *js.Runtime, module *js.Object) {
+			module.Get("exports").(*js.Object).Set("value", v)
+		}
+	}
+
+	vm := js.New()
+	registry := new(Registry)
+	r := registry.Enable(vm)
+	registry.RegisterNativeModule("test/value", value("registry"))
+
+	check := func(expected string) {
+		t.Helper()
+		v, err := vm.RunString(`require("test/value").value`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := v.String(); s != expected {
+			t.Fatalf("Unexpected result: %q", s)
+		}
+	}
+
+	check("registry")
+	r.SetOverlay(map[string]ModuleLoader{"test/value": value("overlay")})
+	check("overlay")
+	r.SetOverlay(nil)
+	check("registry")
+}
+
 func TestRequire(t *testing.T) {
 	const SCRIPT = `
 	var m = require("./testdata/m.js");
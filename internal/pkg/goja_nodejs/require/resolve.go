@@ -18,6 +18,7 @@ func (r *RequireModule) resolve(modpath string) (module *js.Object, err error) {
 
 	module, err = r.loadNative(modpath)
 	if err == nil {
+		r.trackOverlay(module, r.overlay[modpath] != nil)
 		return
 	}
 
@@ -33,20 +34,30 @@ func (r *RequireModule) resolve(modpath string) (module *js.Object, err error) {
 	if strings.HasPrefix(origPath, "./") ||
 		strings.HasPrefix(origPath, "/") || strings.HasPrefix(origPath, "../") ||
 		origPath == "." || origPath == ".." {
+		if err = r.r.checkRequire(origPath, p); err != nil {
+			return
+		}
 		if module, _ = r.modulesCache.Get(p); module != nil {
+			r.trackOverlay(module, false)
 			return
 		}
 		module, err = r.loadAsFileOrDirectory(p)
 		if err == nil && module != nil {
 			r.modulesCache.Add(p, module)
+			r.trackOverlay(module, false)
 		}
 	} else {
+		if err = r.r.checkRequire(origPath, ""); err != nil {
+			return
+		}
 		if module = r.nodeModules[p]; module != nil {
+			r.trackOverlay(module, false)
 			return
 		}
 		module, err = r.loadNodeModules(modpath, start)
 		if err == nil && module != nil {
 			r.nodeModules[p] = module
+			r.trackOverlay(module, false)
 		}
 	}
 
@@ -62,8 +73,11 @@ func (r *RequireModule) loadNative(path string) (*js.Object, error) {
 		return module, nil
 	}
 
-	var ldr ModuleLoader
-	if ldr = r.r.native[path]; ldr == nil {
+	ldr := r.overlay[path]
+	if ldr == nil {
+		ldr = r.r.native[path]
+	}
+	if ldr == nil {
 		ldr = native[path]
 	}
 
@@ -186,7 +200,9 @@ func (r *RequireModule) loadModule(path string) (*js.Object, error) {
 		// 解决循环引用
 		r.modulesCache.Add(path, module)
 		end := r.r.timeTracker.Start("loadModuleFile")
+		r.loading = append(r.loading, module)
 		err := r.loadModuleFile(path, module)
+		r.loading = r.loading[:len(r.loading)-1]
 		end()
 		if err != nil {
 			module = nil
@@ -0,0 +1,315 @@
+package gojsx
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Island is a component rendered on the server and hydrated in the browser, it is created by `<Counter client:load/>`,
+// or by exporting `island` from the component module: `export const island = "load"`.
+type Island struct {
+	Id string
+	// Src is the module path in Option.Fs, it is not written to the html.
+	Src string
+	// Module is the id of Src written to the html as data-module, see Option.IslandModule.
+	Module string
+	// Export is the export name of the component in Src, e.g. "default"
+	Export string
+	// Client is the directive, one of "load", "idle", "visible"
+	Client string
+	// Props is the json of props, children are not included.
+	Props string
+}
+
+// islandMarker 生成追加在模块代码末尾的 js，它会在导出的组件上记录模块路径，
+// jsx-runtime 需要通过它们找到 island 组件对应的文件。
+func islandMarker(src string) []byte {
+	return []byte(fmt.Sprintf(`
+;(function(e, src) {
+	if (!e) return;
+	var client = e.island === true ? "load" : (typeof e.island === "string" ? e.island : "");
+	var mark = function(f, name) {
+		if (typeof f === "function" && !Object.prototype.hasOwnProperty.call(f, "__gojsxIsland")) {
+			Object.defineProperty(f, "__gojsxIsland", {value: {src: src, export: name, client: client}});
+		}
+	};
+	if (typeof e === "function") {
+		mark(e, "default");
+	} else {
+		for (var k in e) mark(e[k], k);
+	}
+})(module.exports, %s);
+`, jsonString(src)))
+}
+
+// defaultIslandModule 是默认的 Island.Module，使用 src 的 hash 避免在 html 中暴露服务器上的文件路径
+func defaultIslandModule(src string) string {
+	h := sha1.Sum([]byte(src))
+	return hex.EncodeToString(h[:8])
+}
+
+type islandModuleOption func(src string) string
+
+func (i islandModuleOption) applyRenderOptions(options *renderOptions) {
+	options.IslandModule = i
+}
+
+// islandModuleOf 返回 src 的 Island.Module
+func (j *Jsx) islandModuleOf(src string) string {
+	if j.islandModule != nil {
+		return j.islandModule(src)
+	}
+	return defaultIslandModule(src)
+}
+
+// addIsland 记录 island 并返回它的 id
+func (ctx *RenderCtx) addIsland(i map[string]interface{}) Island {
+	island := Island{
+		Id: ctx.hydrateIdPrefix + "island-" + fmt.Sprintf("%x", len(ctx.Islands)),
+	}
+	island.Src, _ = i["src"].(string)
+	if ctx.islandModule != nil {
+		island.Module = ctx.islandModule(island.Src)
+	} else {
+		island.Module = defaultIslandModule(island.Src)
+	}
+	island.Export, _ = i["export"].(string)
+	island.Client, _ = i["client"].(string)
+	island.Props, _ = i["props"].(string)
+
+	ctx.Islands = append(ctx.Islands, island)
+	return island
+}
+
+// islandAttributes 返回 island 容器元素 <gojsx-island> 的属性
+func islandAttributes(i Island) map[string]interface{} {
+	return map[string]interface{}{
+		"data-island": i.Id,
+		"data-module": i.Module,
+		"data-export": i.Export,
+		"data-client": i.Client,
+		"data-props":  i.Props,
+	}
+}
+
+type IslandBuildOptions struct {
+	// JSXImportSource is the package providing jsx-runtime in the browser, default is "preact".
+	JSXImportSource string
+	// HydrateImport is the code importing `h` and `hydrate` functions which are used to hydrate islands.
+	// default is `import { h, hydrate } from "preact"`
+	HydrateImport string
+	Minify        bool
+}
+
+// islandEntry 是 island bundle 的入口代码，它会 hydrate 页面中所有使用该组件的 island。
+const islandEntry = `%s
+import * as m from %s;
+
+const C = m[%s];
+const run = (el) => hydrate(h(C, JSON.parse(el.dataset.props || "{}")), el);
+
+document.querySelectorAll('gojsx-island[data-module=' + JSON.stringify(%s) + '][data-export=' + JSON.stringify(%s) + ']').forEach((el) => {
+  switch (el.dataset.client) {
+    case "idle":
+      (window.requestIdleCallback || setTimeout)(() => run(el));
+      break;
+    case "visible":
+      new IntersectionObserver((entries, observer) => {
+        if (entries.some((e) => e.isIntersecting)) {
+          observer.disconnect();
+          run(el);
+        }
+      }).observe(el);
+      break;
+    default:
+      run(el);
+  }
+});
+`
+
+// BuildIsland bundles the island component for the browser by esbuild, the modules are read from Option.Fs.
+// The bundle hydrates all islands in the page which use the component.
+func (j *Jsx) BuildIsland(i Island, o IslandBuildOptions) ([]byte, error) {
+	if o.JSXImportSource == "" {
+		o.JSXImportSource = "preact"
+	}
+	if o.HydrateImport == "" {
+		o.HydrateImport = `import { h, hydrate } from "preact";`
+	}
+
+	src := path.Clean(i.Src)
+	if !path.IsAbs(src) {
+		src = "./" + src
+	}
+	module := i.Module
+	if module == "" {
+		module = j.islandModuleOf(i.Src)
+	}
+	entry := fmt.Sprintf(islandEntry, o.HydrateImport, jsonString(src), jsonString(i.Export), jsonString(module), jsonString(i.Export))
+
+	result := api.Build(api.BuildOptions{
+		Stdin: &api.StdinOptions{
+			Contents:   entry,
+			Sourcefile: "island.js",
+			Loader:     api.LoaderJS,
+		},
+		Bundle:            true,
+		Write:             false,
+		Format:            api.FormatESModule,
+		Platform:          api.PlatformBrowser,
+		JSX:               api.JSXAutomatic,
+		JSXImportSource:   o.JSXImportSource,
+		MinifyWhitespace:  o.Minify,
+		MinifyIdentifiers: o.Minify,
+		MinifySyntax:      o.Minify,
+		Plugins:           []api.Plugin{fsPlugin(j.fs, j.sandbox, path.Dir(src))},
+	})
+	if len(result.Errors) != 0 {
+		er := result.Errors[0]
+		if er.Location != nil {
+			return nil, fmt.Errorf("build island %v error: %v:%v:%v: %v", i.Src, er.Location.File, er.Location.Line, er.Location.Column, er.Text)
+		}
+		return nil, fmt.Errorf("build island %v error: %v", i.Src, er.Text)
+	}
+	if len(result.OutputFiles) == 0 {
+		return nil, fmt.Errorf("build island %v error: no output", i.Src)
+	}
+
+	return result.OutputFiles[0].Contents, nil
+}
+
+const fsNamespace = "gojsx-fs"
+
+// fsPlugin 让 esbuild 从 fs.FS 中解析和读取模块，sandbox 不为 nil 时与 require 一样限制可以导入的模块。
+// 入口代码中的 bare package 从 entryDir（island 所在的目录）开始查找
+func fsPlugin(fileSys fs.FS, sandbox *Sandbox, entryDir string) api.Plugin {
+	return api.Plugin{
+		Name: "gojsx-fs",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				dir := "."
+				if args.Namespace == fsNamespace {
+					dir = path.Dir(args.Importer)
+				} else if !isRelativeModule(args.Path) {
+					dir = entryDir
+				}
+				if sandbox != nil {
+					resolved := ""
+					if isRelativeModule(args.Path) {
+						resolved = joinModule(dir, args.Path)
+					}
+					if err := sandbox.checkRequire(args.Path, resolved); err != nil {
+						return api.OnResolveResult{}, err
+					}
+				}
+				p, err := fsResolve(fileSys, args.Path, dir)
+				if err != nil {
+					return api.OnResolveResult{}, err
+				}
+				return api.OnResolveResult{Path: p, Namespace: fsNamespace}, nil
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: fsNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				if sandbox != nil {
+					if err := sandbox.checkLoad(fileSys, args.Path); err != nil {
+						return api.OnLoadResult{}, err
+					}
+				}
+				bs, err := fs.ReadFile(fileSys, args.Path)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				loader, ok := defaultExtensionToLoaderMap[path.Ext(args.Path)]
+				if !ok {
+					return api.OnLoadResult{}, fmt.Errorf("unsupport file extension(%s) for island", path.Ext(args.Path))
+				}
+				contents := string(trapBOM(bs))
+				return api.OnLoadResult{Contents: &contents, Loader: loader}, nil
+			})
+		},
+	}
+}
+
+var resolveExtensions = []string{".tsx", ".ts", ".jsx", ".js", ".mjs", ".cjs", ".json"}
+
+// isRelativeModule 返回 p 是否是相对或者绝对路径，而不是 bare package
+func isRelativeModule(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || strings.HasPrefix(p, "/") || p == "." || p == ".."
+}
+
+// joinModule 返回 dir 中的模块 p 的路径，绝对路径（如使用 StdFileSystem 时）不受 dir 影响
+func joinModule(dir, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(dir, p)
+}
+
+// fsResolve 以简化的 Node.js 规则在 fs.FS 中查找模块
+func fsResolve(fileSys fs.FS, p string, dir string) (string, error) {
+	if isRelativeModule(p) {
+		if f, ok := fsResolveFile(fileSys, joinModule(dir, p)); ok {
+			return f, nil
+		}
+		return "", fmt.Errorf("can't resolve module: %v", p)
+	}
+
+	for d := dir; ; d = path.Dir(d) {
+		if f, ok := fsResolvePackage(fileSys, path.Join(d, "node_modules", p)); ok {
+			return f, nil
+		}
+		if d == "." || d == "/" {
+			break
+		}
+	}
+
+	return "", fmt.Errorf("can't resolve package: %v", p)
+}
+
+func fsIsFile(fileSys fs.FS, p string) bool {
+	st, err := fs.Stat(fileSys, p)
+	return err == nil && !st.IsDir()
+}
+
+func fsResolveFile(fileSys fs.FS, p string) (string, bool) {
+	if fsIsFile(fileSys, p) {
+		return p, true
+	}
+	for _, ext := range resolveExtensions {
+		if fsIsFile(fileSys, p+ext) {
+			return p + ext, true
+		}
+	}
+	for _, ext := range resolveExtensions {
+		if f := path.Join(p, "index"+ext); fsIsFile(fileSys, f) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+func fsResolvePackage(fileSys fs.FS, p string) (string, bool) {
+	bs, err := fs.ReadFile(fileSys, path.Join(p, "package.json"))
+	if err == nil {
+		var pkg struct {
+			Module string `json:"module"`
+			Main   string `json:"main"`
+		}
+		_ = json.Unmarshal(bs, &pkg)
+		for _, m := range []string{pkg.Module, pkg.Main} {
+			if m == "" {
+				continue
+			}
+			if f, ok := fsResolveFile(fileSys, path.Join(p, m)); ok {
+				return f, true
+			}
+		}
+	}
+
+	return fsResolveFile(fileSys, p)
+}
@@ -0,0 +1,105 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIsland(t *testing.T) {
+	fileSys := fstest.MapFS{
+		"island/Counter.tsx": {Data: []byte(`
+import {useState} from "preact/hooks";
+export default function Counter(props) {
+	const [count, setCount] = useState(props.count)
+	return <button onClick={() => setCount(count + 1)}>{count}</button>
+}
+`)},
+		"island/Clock.tsx": {Data: []byte(`
+export const island = "idle"
+export function Clock() { return <time>now</time> }
+`)},
+		"island/Page.tsx": {Data: []byte(`
+import Counter from "./Counter";
+import {Clock} from "./Clock";
+export default () => <div><Counter client:load count={1}><i></i></Counter><Clock></Clock></div>
+`)},
+		"node_modules/preact/package.json":    {Data: []byte(`{"module": "dist/preact.mjs"}`)},
+		"node_modules/preact/dist/preact.mjs": {Data: []byte(`export function h() {}; export function hydrate() {}`)},
+		"node_modules/preact/hooks/index.js":  {Data: []byte(`export function useState(v) { return [v, () => {}] }`)},
+		"node_modules/preact/jsx-runtime.js":  {Data: []byte(`export function jsx() {}; export const jsxs = jsx; export const Fragment = {}`)},
+	}
+
+	j, err := NewJsx(Option{Fs: fileSys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RegisterModule("preact/hooks", map[string]interface{}{
+		"useState": func(v interface{}) []interface{} { return []interface{}{v, func() {}} },
+	})
+
+	s, ctx, err := j.RenderCtx("./island/Page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counter, clock := defaultIslandModule("island/Counter.tsx"), defaultIslandModule("island/Clock.tsx")
+	assert.Equal(t, `<div><gojsx-island data-client="load" data-export="default" data-island="island-0" data-module="`+counter+`" data-props="{&#34;count&#34;:1}"><button>1</button></gojsx-island><gojsx-island data-client="idle" data-export="Clock" data-island="island-1" data-module="`+clock+`" data-props="{}"><time>now</time></gojsx-island></div>`, s)
+	assert.NotContains(t, s, "island/Counter.tsx")
+	assert.Equal(t, []Island{
+		{Id: "island-0", Src: "island/Counter.tsx", Module: counter, Export: "default", Client: "load", Props: `{"count":1}`},
+		{Id: "island-1", Src: "island/Clock.tsx", Module: clock, Export: "Clock", Client: "idle", Props: `{}`},
+	}, ctx.Islands)
+
+	bundle, err := j.BuildIsland(ctx.Islands[0], IslandBuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(bundle), `function hydrate()`)
+	assert.Contains(t, string(bundle), `function useState(v)`)
+	assert.Contains(t, string(bundle), `setCount(count + 1)`)
+	assert.Contains(t, string(bundle), `[data-module=" + JSON.stringify("`+counter+`")`)
+
+	_, _, err = j.RenderCode([]byte(`function Inline() { return <p></p> }; export default () => <Inline client:load></Inline>`), nil)
+	assert.ErrorContains(t, err, "client:load can only be used on a component exported from a module file: Inline")
+}
+
+func TestIslandStdFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Counter.tsx":                        `export const island = true; export default function Counter(props) { return <button>{props.count}</button> }`,
+		"Page.tsx":                           `import Counter from "./Counter"; export default () => <div><Counter count={1}/></div>`,
+		"node_modules/preact/index.js":       `export function h() {}; export function hydrate() {}`,
+		"node_modules/preact/jsx-runtime.js": `export function jsx() {}; export const jsxs = jsx; export const Fragment = {}`,
+	}
+	for name, code := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	j, err := NewJsx(Option{IslandModule: func(src string) string { return "/islands/" + filepath.Base(src) + ".js" }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ctx, err := j.RenderCtx(filepath.Join(dir, "Page.tsx"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div><gojsx-island data-client="load" data-export="default" data-island="island-0" data-module="/islands/Counter.tsx.js" data-props="{&#34;count&#34;:1}"><button>1</button></gojsx-island></div>`, s)
+	assert.NotContains(t, s, dir)
+	assert.Equal(t, filepath.Join(dir, "Counter.tsx"), ctx.Islands[0].Src)
+
+	bundle, err := j.BuildIsland(ctx.Islands[0], IslandBuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(bundle), `function hydrate()`)
+	assert.Contains(t, string(bundle), `[data-module=" + JSON.stringify("/islands/Counter.tsx.js")`)
+}
@@ -1,6 +1,8 @@
 package gojsx
 
 import (
+	"bufio"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
@@ -12,6 +14,7 @@ import (
 	"github.com/zbysir/gojsx/internal/pkg/goja_nodejs/console"
 	"github.com/zbysir/gojsx/internal/pkg/goja_nodejs/require"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"os"
@@ -21,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Jsx struct {
@@ -34,8 +38,16 @@ type Jsx struct {
 	debug bool
 
 	cache SourceCache
+	fs    fs.FS
 
 	modulesCache *lru.Cache[string, *goja.Program]
+
+	renderPlugins []RenderPlugin
+	limits        Limits
+	sandbox       *Sandbox
+	islandModule  func(src string) string
+	// sources 用于在报错时显示代码片段
+	sources *sourceFiles
 }
 
 type SourceCache interface {
@@ -108,9 +120,13 @@ type execOptions struct {
 	AutoExecJsx      bool
 	AutoExecJsxProps AutoExecJsxProps
 	NativeModules    []nativeModule
+	// Limits 为 nil 时使用 Option.Limits
+	Limits *Limits
+	// RenderOptions 用于 RenderCode 等以 OptionExec 为参数的渲染方法
+	RenderOptions []OptionRender
 }
 
-// WithNativeModule 注意，由于有 vm 对象池公用 vm 的情况，所以只能保证同步执行的代码能正确拿到本次运行的值，如果是第一次运行导出 function 再执行的情况，可能拿到的是第二次运行指定的 Module。
+// WithNativeModule 指定只在本次执行中可见的原生模块，执行结束之后调用导出的 function 时依然可见。
 func WithNativeModule(path string, obj map[string]interface{}) interface {
 	OptionExec
 	OptionRender
@@ -124,8 +140,82 @@ func WithNativeModule(path string, obj map[string]interface{}) interface {
 type AutoExecJsxProps interface{}
 
 type renderOptions struct {
-	Cache         bool
-	NativeModules []nativeModule
+	Cache           bool
+	NativeModules   []nativeModule
+	HydrateIdPrefix string
+	// HydrationScript 为 true 时，会在 </body> 之前插入 RenderCtx.HydrationScript() 生成的代码
+	HydrationScript bool
+	// XML 为 true 时输出 XML（XHTML），见 WithXML
+	XML bool
+	// Pretty 与 Minify 见 WithPretty 与 WithMinify
+	Pretty bool
+	Minify bool
+	// Plugins 见 RenderPlugin
+	Plugins []RenderPlugin
+	// Limits 中的 MaxOutputBytes 与 MaxNodes 用于渲染，见 WithLimits
+	Limits *Limits
+	// IslandModule 见 Option.IslandModule
+	IslandModule func(src string) string
+	ctx          context.Context
+}
+
+type xmlOption bool
+
+func (x xmlOption) applyRenderOptions(options *renderOptions) {
+	options.XML = bool(x)
+}
+
+func (x xmlOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, x)
+}
+
+// WithXML 输出 XML（XHTML），用于生成 RSS、sitemap、svg 文件等：
+//   - 没有子节点的元素都会自闭合，如 <link/>，有子节点的 <link> 也会渲染子节点
+//   - 不会在 <html> 前插入 <!DOCTYPE html>
+//   - <script>、<style> 中的文本会被转义
+//   - 根 <svg>、<math>、<html> 会添加对应的 xmlns
+//   - <CDATA> 会渲染为 <![CDATA[...]]>
+func WithXML(xml bool) interface {
+	OptionExec
+	OptionRender
+} {
+	return xmlOption(xml)
+}
+
+type hydrationScriptOption bool
+
+func (h hydrationScriptOption) applyRenderOptions(options *renderOptions) {
+	options.HydrationScript = bool(h)
+}
+
+func (h hydrationScriptOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, h)
+}
+
+// WithHydrationScript 在 </body> 之前自动插入绑定 hydrate 属性的 <script>，见 RenderCtx.HydrationScript。
+func WithHydrationScript(inject bool) interface {
+	OptionExec
+	OptionRender
+} {
+	return hydrationScriptOption(inject)
+}
+
+type hydrateIdPrefixOption string
+
+func (h hydrateIdPrefixOption) applyRenderOptions(options *renderOptions) {
+	options.HydrateIdPrefix = string(h)
+}
+
+func (h hydrateIdPrefixOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, h)
+}
+
+// WithHydrateIdPrefix 指定 data-hydrate id 的前缀，用于在一个页面中合并多次渲染的结果时避免 id 冲突。
+func WithHydrateIdPrefix(prefix string) interface {
+	OptionExec
+	OptionRender
+} {
+	return hydrateIdPrefixOption(prefix)
 }
 
 type RunJsOption func(*execOptions)
@@ -173,7 +263,7 @@ func (g globalVarOption) applyRunOptions(options *execOptions) {
 	options.GlobalVars[g.k] = g.v
 }
 
-// WithGlobalVar 注意，由于有 vm 对象池公用 vm 的情况，所以只能保证同步执行的代码能正确拿到本次运行的值，如果是第一次运行导出 function 再执行的情况，可能拿到的是第二次运行指定的 GlobalVar。
+// WithGlobalVar 指定只在本次执行中可见的全局变量，执行结束之后调用导出的 function 时依然可见。
 func WithGlobalVar(k string, v interface{}) OptionExec {
 	return globalVarOption{
 		k: k,
@@ -197,67 +287,167 @@ var defaultExecOptions = execOptions{
 
 // ExecCode code 需要是 ESModule 格式，如 export default () => <></>
 func (j *Jsx) ExecCode(src []byte, opts ...OptionExec) (ex *ModuleExport, err error) {
+	return j.ExecCodeContext(context.Background(), src, opts...)
+}
+
+// ExecCodeContext is like ExecCode, but the execution is interrupted when ctx is canceled or its deadline is exceeded,
+// in that case a *TimeoutError is returned.
+func (j *Jsx) ExecCodeContext(ctx context.Context, src []byte, opts ...OptionExec) (ex *ModuleExport, err error) {
 	var p = defaultExecOptions
 	for _, o := range opts {
 		o.applyRunOptions(&p)
 	}
 
-	vm, err := j.getVm()
+	err = j.execCode(ctx, src, p, func(_ context.Context, vm *vmWithRegistry, scope *execScope, v goja.Value) (err error) {
+		ex, err = parseModuleExport(exportGojaValue(v), p.AutoExecJsx, vm, scope)
+		if err != nil {
+			return
+		}
+
+		if p.AutoExecJsx {
+			switch t := ex.Default.(type) {
+			case Callable:
+				// 执行过程中已经持有 vm，不能使用 Callable
+				c, _ := AssertFunction(v.(*goja.Object).Get("default"))
+				v, err := callFunction(vm.vm, c, p.AutoExecJsxProps)
+				if err != nil {
+					return err
+				}
+				// for async components
+				r, err := resolvePromises(vm.vm, v.Export())
+				if err != nil {
+					return err
+				}
+				vd, _ := tryToVDom(r)
+				if vd != nil {
+					ex.Default = vd
+				}
+			case VDom:
+				_, err = resolvePromises(vm.vm, t)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer j.putVm(vm)
+	return
+}
 
-	if !p.Cache {
-		vm.requireModule.Clean() // to clear modules cache
+// execCode 在对象池中取出的 vm 中执行代码，f 用于在 vm 归还之前处理执行结果（如直接渲染 goja.Value）。
+// 执行期间 vm 只能被本次执行使用，p 中的全局变量与原生模块在执行结束之后会被删除，见 execScope。
+// f 的 ctx 包含 Limits.Timeout 限制。
+func (j *Jsx) execCode(ctx context.Context, src []byte, p execOptions, f func(ctx context.Context, vm *vmWithRegistry, scope *execScope, v goja.Value) error) (err error) {
+	fileName := p.FileName
+	if fileName == "" {
+		fileName = "index.js"
 	}
-
-	for _, mod := range p.NativeModules {
-		vm.registry.RegisterNativeModule(mod.Path, func(runtime *goja.Runtime, module *goja.Object) {
-			o := module.Get("exports").(*goja.Object)
-			for k, v := range mod.Obj {
-				_ = o.Set(k, v)
-			}
-		})
+	limits := j.limits
+	if p.Limits != nil {
+		limits = *p.Limits
 	}
+	parent := ctx
+	ctx, cancel := limits.limitContext(ctx)
+	defer cancel()
+	defer func() {
+		err = j.withCodeFrame(limits.limitError(parent, err), fileName, src)
+	}()
 
-	for k, v := range p.GlobalVars {
-		err = vm.vm.Set(k, v)
-		if err != nil {
-			return nil, err
+	vm, err := j.getVm(ctx)
+	if err != nil {
+		return asTimeoutError(ctx, err)
+	}
+
+	// vm 可能正在被之前的执行导出的函数使用（如死循环），等待超时后丢弃它，避免之后的执行也等待它
+	if err = vm.lock(ctx); err != nil {
+		j.discardVm(vm)
+		return err
+	}
+	stop := interruptOnDone(ctx, vm.vm)
+	scope := newExecScope(p, limits)
+	setCallStackSize(vm.vm, limits.MaxCallStackSize)
+	defer func() {
+		stop()
+		vm.exit(scope)
+		setCallStackSize(vm.vm, 0)
+		vm.unlock()
+		// 被中断的 vm 可能处于不一致的状态（如只加载了一半的模块缓存），直接丢弃而不是放回对象池
+		if ctx.Err() != nil {
+			j.discardVm(vm)
+			err = asTimeoutError(ctx, err)
+			return
 		}
+		j.putVm(vm)
+	}()
+
+	if !p.Cache {
+		vm.requireModule.Clean() // to clear modules cache
 	}
 
-	fileName := p.FileName
-	if fileName == "" {
-		fileName = "index.js"
+	if err = vm.enter(scope); err != nil {
+		return err
 	}
 
+	clearPendingErrors(vm.vm)
 	v, err := j.runJs(vm, fileName, src, TransformerFormatIIFE)
 	if err != nil {
 		return
 	}
-	ex, err = parseModuleExport(exportGojaValue(v), p.AutoExecJsx, vm.vm)
-	if err != nil {
+	if err = pendingError(vm.vm); err != nil {
 		return
 	}
 
-	if p.AutoExecJsx {
-		switch t := ex.Default.(type) {
-		case Callable:
-			v, err := t(p.AutoExecJsxProps)
-			if err != nil {
-				return nil, err
-			}
-			vd, _ := tryToVDom(v.Export())
-			if vd != nil {
-				ex.Default = vd
-			}
+	return f(ctx, vm, scope, v)
+}
+
+// renderCodeTo 执行代码并在归还 vm 之前直接渲染 goja.Value，不需要 Export 整个 VDom 树，也能保持属性的顺序。
+func (j *Jsx) renderCodeTo(ctx context.Context, w io.Writer, code []byte, props interface{}, p execOptions, opts []OptionRender) (rctx *RenderCtx, err error) {
+	err = j.execCode(ctx, code, p, func(ctx context.Context, vm *vmWithRegistry, _ *execScope, v goja.Value) error {
+		d, err := callDefaultExport(vm.vm, v, props)
+		if err != nil {
+			return err
 		}
-	}
+
+		opts = append([]OptionRender{renderContextOption{ctx: ctx}}, opts...)
+		if j.limits != (Limits{}) {
+			opts = append([]OptionRender{limitsOption(j.limits)}, opts...)
+		}
+		if len(j.renderPlugins) != 0 {
+			opts = append([]OptionRender{renderPluginsOption(j.renderPlugins)}, opts...)
+		}
+		if j.islandModule != nil {
+			opts = append([]OptionRender{islandModuleOption(j.islandModule)}, opts...)
+		}
+		rctx, err = renderTo(w, vm.vm, d, opts...)
+		return err
+	})
 	return
 }
 
+// callDefaultExport 返回模块的默认导出，如果是函数则使用 props 调用它
+func callDefaultExport(vm *goja.Runtime, exports goja.Value, props interface{}) (goja.Value, error) {
+	o, ok := exports.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("export value type expect 'object', actual '%T'", exports.Export())
+	}
+	d := o.Get("default")
+	if isNullish(d) {
+		return nil, fmt.Errorf("module has no default export")
+	}
+
+	if c, ok := AssertFunction(d); ok {
+		v, err := c(nil, vm.ToValue(props))
+		if err != nil {
+			return nil, err
+		}
+		return v, pendingError(vm)
+	}
+	return d, nil
+}
+
 func (j *Jsx) runJs(vm *vmWithRegistry, fileName string, src []byte, transform TransformerFormat) (v goja.Value, err error) {
 	if transform != 0 {
 		key := mD5(src)
@@ -297,14 +487,17 @@ func (j *Jsx) runJs(vm *vmWithRegistry, fileName string, src []byte, transform T
 }
 
 type vmWithRegistry struct {
+	// busy 在执行期间以及调用导出的函数时持有，避免 vm 被对象池借出之后依然被之前的执行使用，见 lock
+	busy          chan struct{}
+	saved         []savedGlobal
 	once          sync.Once
 	vm            *goja.Runtime
 	registry      *require.Registry
 	requireModule *require.RequireModule
 }
 
-func (j *Jsx) getVm() (*vmWithRegistry, error) {
-	vm, err := j.vmPool.Get()
+func (j *Jsx) getVm(ctx context.Context) (*vmWithRegistry, error) {
+	vm, err := j.vmPool.Get(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("pool.Get error: %w", err)
 	}
@@ -316,56 +509,103 @@ func (j *Jsx) putVm(v *vmWithRegistry) error {
 	return j.vmPool.Put(v)
 }
 
+// PoolStats returns the statistics of the vm pool, it can be used to tune the Vm* fields of Option.
+func (j *Jsx) PoolStats() PoolStats {
+	return j.vmPool.Stats()
+}
+
+// Close releases the vm pool and stops the goroutine checking idle vms (see Option.VmMaxIdleTime),
+// the Jsx can't be used after Close.
+func (j *Jsx) Close() error {
+	j.vmPool.Close()
+	return nil
+}
+
+// discardVm destroy the vm instead of putting it back to the pool
+func (j *Jsx) discardVm(v *vmWithRegistry) error {
+	return j.vmPool.Invalidate(v)
+}
+
 // Render a component to html
 func (j *Jsx) Render(file string, props interface{}, opts ...OptionRender) (n string, err error) {
 	n, _, err = j.RenderCtx(file, props, opts...)
 	return n, err
 }
 
+// RenderContext is like Render, but the execution is interrupted when ctx is done.
+func (j *Jsx) RenderContext(ctx context.Context, file string, props interface{}, opts ...OptionRender) (n string, err error) {
+	var s strings.Builder
+	_, err = j.RenderToContext(ctx, &s, file, props, opts...)
+	if err != nil {
+		return
+	}
+	return s.String(), nil
+}
+
 // RenderCode code to html
 func (j *Jsx) RenderCode(code []byte, props interface{}, opts ...OptionExec) (n string, ctx *RenderCtx, err error) {
-	opts = append(opts, WithAutoExecJsx(props))
-	ex, err := j.ExecCode(code, opts...)
+	return j.RenderCodeContext(context.Background(), code, props, opts...)
+}
+
+// RenderCodeContext is like RenderCode, but the execution is interrupted when ctx is done.
+func (j *Jsx) RenderCodeContext(ctx context.Context, code []byte, props interface{}, opts ...OptionExec) (n string, rctx *RenderCtx, err error) {
+	var s strings.Builder
+	rctx, err = j.RenderCodeToContext(ctx, &s, code, props, opts...)
 	if err != nil {
 		return
 	}
+	return s.String(), rctx, nil
+}
 
-	switch t := ex.Default.(type) {
-	case VDom:
-		s, ctx := t.Render()
-		return s, ctx, nil
-	default:
-		log.Panicf("unspoort export type: %T, shound be a vdom", ex.Default)
+// RenderCodeTo render code to w, html is written incrementally instead of being built in memory.
+func (j *Jsx) RenderCodeTo(w io.Writer, code []byte, props interface{}, opts ...OptionExec) (ctx *RenderCtx, err error) {
+	return j.RenderCodeToContext(context.Background(), w, code, props, opts...)
+}
+
+// RenderCodeToContext is like RenderCodeTo, but the execution is interrupted when ctx is done.
+func (j *Jsx) RenderCodeToContext(ctx context.Context, w io.Writer, code []byte, props interface{}, opts ...OptionExec) (rctx *RenderCtx, err error) {
+	var p = defaultExecOptions
+	for _, o := range opts {
+		o.applyRunOptions(&p)
 	}
-	return
+
+	return j.renderCodeTo(ctx, w, code, props, p, p.RenderOptions)
 }
 
 // RenderCtx a component to html
 func (j *Jsx) RenderCtx(file string, props interface{}, opts ...OptionRender) (n string, ctx *RenderCtx, err error) {
+	var s strings.Builder
+	ctx, err = j.RenderTo(&s, file, props, opts...)
+	if err != nil {
+		return
+	}
+	return s.String(), ctx, nil
+}
+
+// RenderTo render a component to w, html is written incrementally instead of being built in memory.
+// If w is a http.ResponseWriter, it will be flushed after rendering.
+func (j *Jsx) RenderTo(w io.Writer, file string, props interface{}, opts ...OptionRender) (ctx *RenderCtx, err error) {
+	return j.RenderToContext(context.Background(), w, file, props, opts...)
+}
+
+// RenderToContext is like RenderTo, but the execution is interrupted when ctx is done.
+func (j *Jsx) RenderToContext(ctx context.Context, w io.Writer, file string, props interface{}, opts ...OptionRender) (rctx *RenderCtx, err error) {
 	var p renderOptions
 	for _, o := range opts {
 		o.applyRenderOptions(&p)
 	}
 
-	var eo = []OptionExec{
-		WithCache(p.Cache), WithAutoExecJsx(props),
-	}
-	for _, m := range p.NativeModules {
-		eo = append(eo, WithNativeModule(m.Path, m.Obj))
-	}
-	ex, err := j.Exec(file, eo...)
-	if err != nil {
-		return
-	}
+	var e = defaultExecOptions
+	e.Cache = p.Cache
+	e.NativeModules = p.NativeModules
+	e.Limits = p.Limits
 
-	switch t := ex.Default.(type) {
-	case VDom:
-		s, ctx := t.Render()
-		return s, ctx, nil
-	default:
-		panic(t)
-	}
-	return
+	return j.renderCodeTo(ctx, w, requireCode(file), props, e, opts)
+}
+
+// requireCode 生成导出 file 模块的代码
+func requireCode(file string) []byte {
+	return []byte(fmt.Sprintf(`module.exports = require("%v")`, file))
 }
 
 // 和 goja 自己的 export 不一样的是，不会尝试导出单个变量为 golang 基础类型，而是保留 goja.Value，只是展开 Object
@@ -395,14 +635,12 @@ func exportGojaValue(i interface{}) interface{} {
 }
 
 func (j *Jsx) Exec(file string, opts ...OptionExec) (ex *ModuleExport, err error) {
-	var p execOptions
-	for _, o := range opts {
-		o.applyRunOptions(&p)
-	}
-
-	var code = []byte(fmt.Sprintf(`module.exports = require("%v")`, file))
+	return j.ExecContext(context.Background(), file, opts...)
+}
 
-	ex, err = j.ExecCode(code, opts...)
+// ExecContext is like Exec, but the execution is interrupted when ctx is canceled or its deadline is exceeded.
+func (j *Jsx) ExecContext(ctx context.Context, file string, opts ...OptionExec) (ex *ModuleExport, err error) {
+	ex, err = j.ExecCodeContext(ctx, requireCode(file), opts...)
 	if err != nil {
 		return
 	}
@@ -435,6 +673,7 @@ type ModuleExport struct {
 	// VDom if WithAutoExecJsx
 	// Callable if export a function
 	Default ExportDefault
+	// Exports are the other exports, exported functions are Callable (they were *goja.Object before)
 	Exports map[string]interface{}
 }
 
@@ -462,7 +701,7 @@ type VDomOrInterface struct {
 //	return "", nil
 //}
 
-func parseModuleExport(i interface{}, tryVDom bool, vm *goja.Runtime) (m *ModuleExport, err error) {
+func parseModuleExport(i interface{}, tryVDom bool, vm *vmWithRegistry, scope *execScope) (m *ModuleExport, err error) {
 	var vDomOrInterface ExportDefault
 
 	switch t := i.(type) {
@@ -471,13 +710,7 @@ func parseModuleExport(i interface{}, tryVDom bool, vm *goja.Runtime) (m *Module
 		case *goja.Object:
 			c, ok := AssertFunction(t)
 			if ok {
-				vDomOrInterface = Callable(func(args ...interface{}) (v goja.Value, err error) {
-					as := make([]goja.Value, len(args))
-					for i, arg := range args {
-						as[i] = vm.ToValue(arg)
-					}
-					return c(nil, as...)
-				})
+				vDomOrInterface = vm.callable(scope, c)
 			} else {
 				vDomOrInterface = Any{t.Export()}
 			}
@@ -496,6 +729,13 @@ func parseModuleExport(i interface{}, tryVDom bool, vm *goja.Runtime) (m *Module
 		}
 
 		delete(t, "default")
+		for k, v := range t {
+			if o, ok := v.(*goja.Object); ok {
+				if c, ok := AssertFunction(o); ok {
+					t[k] = vm.callable(scope, c)
+				}
+			}
+		}
 		return &ModuleExport{
 			Default: vDomOrInterface,
 			Exports: t,
@@ -521,7 +761,7 @@ func (j *Jsx) RegisterModule(name string, obj map[string]interface{}) {
 	require.RegisterNativeModule(name, func(runtime *goja.Runtime, module *goja.Object) {
 		o := module.Get("exports").(*goja.Object)
 		for k, v := range obj {
-			_ = o.Set(k, v)
+			_ = o.Set(k, moduleValue(runtime, k, v))
 		}
 	})
 }
@@ -535,11 +775,36 @@ func (f stdFileSystem) Open(name string) (fs.File, error) {
 	return os.Open(name)
 }
 
+func (f stdFileSystem) realPath(name string) (string, error) {
+	p, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return "", err
+	}
+	p, err = filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(p), nil
+}
+
 type Option struct {
 	SourceCache SourceCache
 	Debug       bool // enable to get more log
 	// 最多的 vm 对象数量，指定为 1 表示只会同时有一个 vm 运行，默认为 2000
-	VmMaxTotal  int
+	VmMaxTotal int
+	// VmMaxIdle 是最多保留的空闲 vm 数量，默认不限制
+	VmMaxIdle int
+	// VmMinIdle 是至少保留的空闲 vm 数量，在每次检查空闲 vm 时补充（见 VmEvictionInterval）
+	VmMinIdle int
+	// VmPrewarm 是 NewJsx 时预先创建的 vm 数量
+	VmPrewarm int
+	// VmMaxIdleTime 是 vm 最长的空闲时间，超过后会被回收（但会保留 VmMinIdle 个），默认不回收
+	VmMaxIdleTime time.Duration
+	// VmEvictionInterval 是检查空闲 vm 的间隔，指定了 VmMaxIdleTime 或 VmMinIdle 时默认为 1 分钟
+	VmEvictionInterval time.Duration
+	// VmBorrowTimeout 是等待空闲 vm 的最长时间，超过后返回 *PoolExhaustedError，默认一直等待（直到 ctx 结束）
+	VmBorrowTimeout time.Duration
+
 	Transformer Transformer
 	// Fs 没办法做到每次执行代码时指定，因为 require 可能会发生在异步 function 里，fs 改变会导致加载文件错误
 	Fs fs.FS // default is StdFileSystem
@@ -547,6 +812,19 @@ type Option struct {
 	// GojaFieldNameMapper Specify the mapping of field names in go struct and js.
 	// via: https://github.com/dop251/goja#mapping-struct-field-and-method-names
 	GojaFieldNameMapper goja.FieldNameMapper
+
+	// RenderPlugins 在每次渲染时处理所有元素，如重写 url、添加属性，见 RenderPlugin
+	RenderPlugins []RenderPlugin
+
+	// Limits 限制每次执行使用的资源，可以使用 WithLimits 为一次执行单独指定
+	Limits Limits
+	// Sandbox 限制 js 可以导入的模块，为 nil 表示不限制
+	Sandbox *Sandbox
+	// DisableEval 禁止 js 将字符串作为代码执行，如 eval、new Function，见 disableEvalScript
+	DisableEval bool
+	// IslandModule 返回 island 的模块在 html 中的标识（data-module），如 bundle 的 url，见 Island.Module。
+	// 默认为 Src 的 hash，不会暴露服务器上的文件路径
+	IslandModule func(src string) string
 }
 
 var defaultFieldNameMapper = TagFieldNameMapper("json", true, true)
@@ -555,6 +833,10 @@ func NewJsx(op Option) (*Jsx, error) {
 	if op.VmMaxTotal <= 0 {
 		op.VmMaxTotal = 2000
 	}
+	maxIdle := op.VmMaxIdle
+	if maxIdle <= 0 {
+		maxIdle = -1
+	}
 
 	if op.Transformer == nil {
 		op.Transformer = NewEsBuildTransform(EsBuildTransformOptions{})
@@ -575,42 +857,70 @@ func NewJsx(op Option) (*Jsx, error) {
 	if err != nil {
 		return nil, err
 	}
+	sources := &sourceFiles{}
 
 	j := &Jsx{
-		vmPool: newTPool(op.VmMaxTotal, func() *vmWithRegistry {
+		vmPool: newTPool(poolConfig{
+			maxTotal:         op.VmMaxTotal,
+			maxIdle:          maxIdle,
+			minIdle:          op.VmMinIdle,
+			prewarm:          op.VmPrewarm,
+			maxIdleTime:      op.VmMaxIdleTime,
+			evictionInterval: op.VmEvictionInterval,
+			borrowTimeout:    op.VmBorrowTimeout,
+		}, func() *vmWithRegistry {
 			vm := goja.New()
 			vm.SetFieldNameMapper(op.GojaFieldNameMapper)
 
 			if op.Debug {
 				log.Printf("new vm")
 			}
-			registry := require.NewRegistryWithLoader(registryLoader(op.Fs, op.SourceCache, op.Transformer))
+			registryOptions := []require.Option{require.WithLoader(registryLoader(op.Fs, op.SourceCache, op.Transformer, sources, op.Sandbox))}
+			if op.Sandbox != nil {
+				registryOptions = append(registryOptions, require.WithRequireHook(op.Sandbox.checkRequire))
+			}
+			registry := require.NewRegistry(registryOptions...)
 			requireModule := registry.Enable(vm)
 
 			console.Enable(vm, nil)
 
+			if op.DisableEval {
+				// disableEvalScript 是固定的代码，不会出错
+				if err := disableEval(vm); err != nil {
+					panic(err)
+				}
+			}
+
 			return &vmWithRegistry{
+				busy:          make(chan struct{}, 1),
 				once:          sync.Once{},
 				vm:            vm,
 				registry:      registry,
 				requireModule: requireModule,
 			}
 		}),
-		tr:           op.Transformer,
-		lock:         sync.Mutex{},
-		debug:        op.Debug,
-		cache:        op.SourceCache,
-		modulesCache: jsProgramCache,
+		tr:            op.Transformer,
+		lock:          sync.Mutex{},
+		debug:         op.Debug,
+		cache:         op.SourceCache,
+		fs:            op.Fs,
+		modulesCache:  jsProgramCache,
+		renderPlugins: op.RenderPlugins,
+		limits:        op.Limits,
+		sandbox:       op.Sandbox,
+		islandModule:  op.IslandModule,
+		sources:       sources,
 	}
 
 	return j, nil
 }
 
-func registryLoader(fileSys fs.FS, cache SourceCache, tr Transformer) require.SourceLoader {
+func registryLoader(fileSys fs.FS, cache SourceCache, tr Transformer, sources *sourceFiles, sandbox *Sandbox) require.SourceLoader {
 	return func(path string) ([]byte, error) {
 		var fileBody []byte
 
-		if strings.HasSuffix(path, "node_modules/react/jsx-runtime") {
+		// gojsx 与 react/jsx-runtime 相同，用于导入 Head 等内置组件
+		if strings.HasSuffix(path, "node_modules/react/jsx-runtime") || strings.HasSuffix(path, "node_modules/gojsx") {
 			fileBody = js.JsxRuntime
 		}
 
@@ -633,16 +943,30 @@ func registryLoader(fileSys fs.FS, cache SourceCache, tr Transformer) require.So
 				if p != "" {
 					tryPath = strings.TrimSuffix(path, ".js") + p
 				}
+				if sandbox != nil {
+					if err := sandbox.checkLoad(fileSys, tryPath); err != nil {
+						// 存在但是不能读取的文件返回 *ModuleAccessError，而不是找不到模块
+						if st, serr := fs.Stat(fileSys, tryPath); serr == nil && !st.IsDir() {
+							return nil, err
+						}
+						continue
+					}
+				}
 				bs, err := fs.ReadFile(fileSys, tryPath)
 				if err != nil {
 					if errors.Is(err, fs.ErrNotExist) || strings.Contains(err.Error(), "is a directory") {
 						continue
 					}
+					// 如 fstest.MapFS 读取目录时返回的不是 "is a directory"
+					if st, serr := fs.Stat(fileSys, tryPath); serr == nil && st.IsDir() {
+						continue
+					}
 					return nil, fmt.Errorf("can't load module: %v, error: %w", path, err)
 				}
 				find = true
 				path = tryPath
 				fileBody = bs
+				sources.set(path, bs)
 				break
 			}
 			if !find {
@@ -681,6 +1005,13 @@ func registryLoader(fileSys fs.FS, cache SourceCache, tr Transformer) require.So
 			}
 		}
 
+		// 记录组件所在的模块，用于 island
+		switch filepath.Ext(path) {
+		case ".json", "":
+		default:
+			fileBody = appendBeforeSourceMap(fileBody, islandMarker(path))
+		}
+
 		return fileBody, nil
 	}
 }
@@ -696,6 +1027,10 @@ type VDom map[string]interface{}
 // 参考 react-dom/cjs/react-dom-server-legacy.node.development.js 实现
 var propsToAttr = map[string]string{}
 var boolAttr = map[string]bool{} // 如果是 boolAttr，当传递了 attr value 则会渲染，否则不会渲染整个 attr key
+var booleanishAttr = map[string]bool{}
+var overloadedBoolAttr = map[string]bool{}
+var positiveNumericAttr = map[string]bool{}
+var numericAttr = map[string]bool{}
 
 func init() {
 	// A few React string attributes have a different name. This is a mapping from React prop names to the attribute names.
@@ -716,7 +1051,23 @@ func init() {
 
 	// These attribute exists both in HTML and SVG. The attribute name is case-sensitive in SVG so we can't just use the React name like we do for attributes that exist only in HTML.
 	for _, a := range []string{"tabIndex", "crossOrigin"} {
-		propsToAttr[a] = a
+		propsToAttr[a] = strings.ToLower(a)
+	}
+
+	// These are XLink and XML namespaced attributes.
+	for k, v := range map[string]string{
+		"xlinkActuate": "xlink:actuate",
+		"xlinkArcrole": "xlink:arcrole",
+		"xlinkRole":    "xlink:role",
+		"xlinkShow":    "xlink:show",
+		"xlinkTitle":   "xlink:title",
+		"xlinkType":    "xlink:type",
+		"xlinkHref":    "xlink:href",
+		"xmlBase":      "xml:base",
+		"xmlLang":      "xml:lang",
+		"xmlSpace":     "xml:space",
+	} {
+		propsToAttr[k] = v
 	}
 
 	// These are HTML boolean attributes.
@@ -724,6 +1075,60 @@ func init() {
 		propsToAttr[a] = strings.ToLower(a)
 		boolAttr[strings.ToLower(a)] = true
 	}
+	// These are the few React props that we set as DOM properties rather than attributes. These are all booleans.
+	for _, a := range []string{"checked", "multiple", "muted", "selected"} {
+		boolAttr[a] = true
+	}
+
+	// These are "enumerated" HTML attributes that accept "true" and "false". In React, we let users pass `true` and `false` even though technically these aren't boolean attributes (they are coerced to strings).
+	for _, a := range []string{"contentEditable", "draggable", "spellCheck", "value"} {
+		propsToAttr[a] = strings.ToLower(a)
+		booleanishAttr[strings.ToLower(a)] = true
+	}
+	// These are "enumerated" SVG attributes that accept "true" and "false". Since these are SVG attributes, their attribute names are case-sensitive.
+	for _, a := range []string{"autoReverse", "externalResourcesRequired", "focusable", "preserveAlpha"} {
+		booleanishAttr[a] = true
+	}
+
+	// These are HTML attributes that are "overloaded booleans": they behave like booleans, but can also accept a string value.
+	for _, a := range []string{"capture", "download"} {
+		overloadedBoolAttr[a] = true
+	}
+
+	// These are HTML attributes that must be positive numbers.
+	for _, a := range []string{"cols", "rows", "size", "span"} {
+		positiveNumericAttr[a] = true
+	}
+	// These are HTML attributes that must be numbers.
+	for _, a := range []string{"rowSpan", "start"} {
+		propsToAttr[a] = strings.ToLower(a)
+		numericAttr[strings.ToLower(a)] = true
+	}
+}
+
+// renderWriter 记录第一次写入时发生的错误，之后的写入都会被忽略，这样渲染过程中不需要在每一处都检查错误。
+type renderWriter struct {
+	w   io.Writer
+	err error
+	// max 是最多写入的字节数，0 表示不限制，见 Limits.MaxOutputBytes
+	max     int64
+	written int64
+}
+
+func (r *renderWriter) WriteString(s string) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.max > 0 && r.written+int64(len(s)) > r.max {
+		r.err = &LimitError{Kind: LimitOutputBytes, Limit: r.max}
+		return 0, r.err
+	}
+	r.written += int64(len(s))
+	n, err := io.WriteString(r.w, s)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
 }
 
 func sortMap(ps map[string]interface{}, f func(k string, v interface{})) {
@@ -739,43 +1144,51 @@ func sortMap(ps map[string]interface{}, f func(k string, v interface{})) {
 	}
 }
 
-var currHydrateId = 0
-
-func renderAttributes(s *strings.Builder, ctx *RenderCtx, props map[string]interface{}) {
+func renderAttributes(s io.StringWriter, ctx *RenderCtx, props []attribute) {
 	if len(props) == 0 {
 		return
 	}
 
 	// 如果 attr 里同时存在 class 和 className，则会将 class 放到 className 里统一处理。
-	if c, ok := props["class"]; ok {
-		if cn, ok := props["className"]; ok {
-			props["className"] = []interface{}{cn, c}
+	ci, cni := -1, -1
+	for i, a := range props {
+		switch a.key {
+		case "class":
+			ci = i
+		case "className":
+			cni = i
+		}
+	}
+	if ci != -1 {
+		props = append([]attribute(nil), props...)
+		if cni != -1 {
+			props[cni].value = []interface{}{props[cni].value, props[ci].value}
+			props = append(props[:ci], props[ci+1:]...)
 		} else {
-			props["className"] = c
+			props[ci].key = "className"
 		}
-
-		delete(props, "class")
 	}
 
-	var hydrate = map[string]string{}
-	// 转为 16 进制
-	var hydrateId = fmt.Sprintf("%x", currHydrateId)
-	currHydrateId++
+	// 只有存在 hydrate 属性时才分配 id
+	var hydrate map[string]string
+	var hydrateId string
 
-	// 稳定顺序
-	// TODO 考虑直接使用 goja.Object 用作参数，不直接使用 Export 出来的 map，这样能保留字段排序。
-	sortMap(props, func(k string, val interface{}) {
+	for _, a := range props {
+		k, val := a.key, a.value
 		if k == "children" || k == "dangerouslySetInnerHTML" {
-			return
+			continue
 		}
 		if strings.HasPrefix(k, "hydrate") {
-			s.WriteString(` data-hydrate="`)
-			s.WriteString(hydrateId)
-			s.WriteString(`"`)
-			//
-			//log.Printf("renderAttributes: %s,val: %#v", k, val)
+			if hydrateId == "" {
+				hydrateId = ctx.nextHydrateId()
+				hydrate = map[string]string{}
+				s.WriteString(` data-hydrate="`)
+				s.WriteString(hydrateId)
+				s.WriteString(`"`)
+			}
+			// 函数会得到它的源码
 			hydrate[k] = fmt.Sprintf(`%s`, val)
-			return
+			continue
 		}
 		switch k {
 		case "className":
@@ -785,77 +1198,24 @@ func renderAttributes(s *strings.Builder, ctx *RenderCtx, props map[string]inter
 			}
 			s.WriteString(`"`)
 		case "style":
-			s.WriteString(` style="`)
-			renderStyle(s, val)
-			s.WriteString(`"`)
-		default:
-			if n, ok := propsToAttr[k]; ok {
-				k = n
-			}
-
-			if boolAttr[k] {
-				tr := false
-				switch t := val.(type) {
-				case string:
-					tr = strings.ToLower(t) == "true"
-				case int, int32, int16, int8, int64, float64, float32:
-					tr = val != 0
-				case bool:
-					tr = t
-				}
-				if tr {
-					s.WriteString(" ")
-					s.WriteString(k)
-				}
-			} else {
-				vs := attributeValueToString(val)
-				if vs != "" {
-					s.WriteString(" ")
-					if n, ok := propsToAttr[k]; ok {
-						s.WriteString(n)
-					} else {
-						s.WriteString(k)
-					}
-
-					s.WriteString(`=`)
-					s.WriteString(`"`)
-					s.WriteString(vs)
-					s.WriteString(`"`)
-				}
+			// 与 react 一致，没有任何有效的值时不输出 style 属性
+			var b strings.Builder
+			renderStyle(&b, val)
+			if b.Len() != 0 {
+				s.WriteString(` style="`)
+				s.WriteString(b.String())
+				s.WriteString(`"`)
 			}
+		default:
+			renderAttribute(s, k, val)
 		}
-	})
+	}
 
 	if len(hydrate) > 0 {
 		ctx.AddHydrate(hydrateId, hydrate)
 	}
 }
 
-func renderAttributeValue(s *strings.Builder, val interface{}) {
-	// 只支持 string/int
-	switch t := val.(type) {
-	case string:
-		s.WriteString(`"`)
-		s.WriteString(template.HTMLEscapeString(t))
-		s.WriteString(`"`)
-	case int, int64, int32, int16, int8, float64, float32:
-		s.WriteString(`"`)
-		s.WriteString(fmt.Sprintf("%v", t))
-		s.WriteString(`"`)
-	}
-}
-
-func attributeValueToString(val interface{}) string {
-	// 只支持 string/int
-	switch t := val.(type) {
-	case string:
-		return template.HTMLEscapeString(t)
-	case int, int64, int32, int16, int8, float64, float32, bool, uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf(`%v`, t)
-	}
-	return ""
-}
-
 // cleanClass delete \n and extra space
 func cleanClass(c string) string {
 	var s strings.Builder
@@ -881,7 +1241,7 @@ func cleanClass(c string) string {
 	return s.String()
 }
 
-func renderClassName(s *strings.Builder, className interface{}, isFirst bool) {
+func renderClassName(s io.StringWriter, className interface{}, isFirst bool) {
 	switch t := className.(type) {
 	case []interface{}:
 		for index, i := range t {
@@ -895,28 +1255,51 @@ func renderClassName(s *strings.Builder, className interface{}, isFirst bool) {
 	}
 }
 
-func renderStyle(s *strings.Builder, val interface{}) {
+// renderStyle 渲染 style 属性的值（已转义），值为空的属性会被忽略
+func renderStyle(s io.StringWriter, val interface{}) {
 	isFirst := true
+	write := func(k string, value string) {
+		if value == "" {
+			return
+		}
+		if isFirst {
+			isFirst = false
+		} else {
+			s.WriteString(" ")
+		}
+		s.WriteString(template.HTMLEscapeString(styleName(k)))
+		s.WriteString(":")
+		s.WriteString(" ")
+		s.WriteString(template.HTMLEscapeString(value))
+		s.WriteString(";")
+	}
+	item := func(k string, v interface{}) {
+		if value, ok := styleValue(k, v); ok {
+			write(k, value)
+		}
+	}
 	switch t := val.(type) {
 	case map[string]interface{}:
 		// for style={{color: "red"}}
-		sortMap(t, func(k string, v interface{}) {
-			if isFirst {
-				isFirst = false
-			} else {
-				s.WriteString(" ")
+		sortMap(t, item)
+	case *goja.Object:
+		// 保持 style 中的顺序
+		for _, k := range t.Keys() {
+			v := t.Get(k)
+			if str, ok := gojaString(v); ok {
+				write(k, strings.TrimSpace(str))
+				continue
 			}
-			s.WriteString(hyphenateStyleName(k))
-			s.WriteString(":")
-			s.WriteString(" ")
-			s.WriteString(fmt.Sprintf("%v", v))
-			s.WriteString(";")
-		})
+			item(k, gojaExport(v))
+		}
+	case nil, bool:
 	case string:
 		// for style=""
-		s.WriteString(t)
+		s.WriteString(template.HTMLEscapeString(t))
 	default:
-		s.WriteString(fmt.Sprintf("%v", t))
+		if !isNullish(t) {
+			s.WriteString(template.HTMLEscapeString(fmt.Sprintf("%v", t)))
+		}
 	}
 }
 
@@ -946,7 +1329,7 @@ func hyphenateStyleName(s string) string {
 	s = ToKebabCase(s)
 
 	// "msTransition" -> "-ms-transition",
-	if strings.HasPrefix(s, "ms-") || strings.HasPrefix(s, "moz-") {
+	if strings.HasPrefix(s, "ms-") || strings.HasPrefix(s, "moz-") || strings.HasPrefix(s, "webkit-") {
 		s = "-" + s
 	}
 
@@ -1021,13 +1404,116 @@ func (v VDom) string(indent int) string {
 	return s.String()
 }
 
-func (v VDom) Render() (string, *RenderCtx) {
-	return Render(v)
+func (v VDom) Render(opts ...OptionRender) (string, *RenderCtx) {
+	return Render(v, opts...)
+}
+
+// RenderTo write html to w
+func (v VDom) RenderTo(w io.Writer, opts ...OptionRender) (*RenderCtx, error) {
+	return RenderTo(w, v, opts...)
 }
 
 type RenderCtx struct {
 	// Hydrate 用于将组件的数据提取到单独的文件中。
 	Hydrate map[string]map[string]string // id => [event type => event code]
+
+	// hydrateId 在每次渲染中从 0 开始递增，保证同一页面多次渲染的 id 是稳定的。
+	hydrateId       int
+	hydrateIdPrefix string
+	hydrateOrder    []string
+
+	injectHydrationScript bool
+
+	// Islands are the components need to be hydrated in the browser, see Jsx.BuildIsland.
+	Islands []Island
+
+	// Errors are the errors thrown by components and caught by <ErrorBoundary>, the fallback is rendered instead.
+	Errors []error
+
+	// nodes 是已经渲染的元素数量，见 Limits.MaxNodes
+	nodes          int
+	maxNodes       int
+	maxOutputBytes int64
+	// execCtx 结束时停止渲染
+	execCtx context.Context
+
+	// Head is the rendered html of tags in <head>, including tags hoisted by <Head> from nested components.
+	// If the document has no <head>, it can be used to render the <head> by yourself.
+	Head []string
+
+	root         interface{}
+	headTags     []headTag
+	headRendered bool
+
+	// vm 用于渲染 goja.Value（如等待 Promise），只在渲染期间有效
+	vm *goja.Runtime
+	// form 用于渲染 <select value> 中的 <option>
+	form formState
+	// rawText 用于转义 raw text 元素（如 <script>）中的文本，为 nil 时使用 html 转义
+	rawText func(string) string
+
+	xml bool
+	// ns 是当前元素的命名空间，为空表示 html
+	ns string
+	// xmlns 是 xml 模式中已经声明的默认命名空间
+	xmlns string
+
+	pretty bool
+	minify bool
+	// depth 是 pretty 模式中的缩进层级
+	depth int
+	// preserve 大于 0 时表示在 pre 等元素中，需要保持空白字符
+	preserve int
+	// next 与 parent 是 minify 模式中正在渲染的元素的下一个兄弟节点与父元素，siblingKnown 为 false 时表示未知
+	next         string
+	parent       string
+	siblingKnown bool
+
+	plugins      []RenderPlugin
+	islandModule func(src string) string
+
+	// attrs 用于分配 goja 节点的属性列表，见 allocAttrs
+	attrs []attribute
+}
+
+func newRenderCtx(opts ...OptionRender) *RenderCtx {
+	var p renderOptions
+	for _, o := range opts {
+		o.applyRenderOptions(&p)
+	}
+
+	var limits Limits
+	if p.Limits != nil {
+		limits = *p.Limits
+	}
+
+	return &RenderCtx{
+		hydrateIdPrefix:       p.HydrateIdPrefix,
+		injectHydrationScript: p.HydrationScript,
+		xml:                   p.XML,
+		pretty:                p.Pretty,
+		minify:                p.Minify,
+		plugins:               p.Plugins,
+		islandModule:          p.IslandModule,
+		maxNodes:              limits.MaxNodes,
+		maxOutputBytes:        limits.MaxOutputBytes,
+		execCtx:               p.ctx,
+	}
+}
+
+// escapeText 转义文本节点
+func (ctx *RenderCtx) escapeText(s string) string {
+	if ctx.rawText != nil {
+		return ctx.rawText(s)
+	}
+	return template.HTMLEscapeString(ctx.collapseWhitespace(s))
+}
+
+// nextHydrateId 返回下一个 hydrate id，格式为 prefix + 16 进制的序号
+func (ctx *RenderCtx) nextHydrateId() string {
+	id := ctx.hydrateIdPrefix + strconv.FormatInt(int64(ctx.hydrateId), 16)
+	ctx.hydrateId++
+	return id
 }
 
 // AddHydrate add hydrate
@@ -1035,119 +1521,326 @@ func (ctx *RenderCtx) AddHydrate(id string, props map[string]string) {
 	if ctx.Hydrate == nil {
 		ctx.Hydrate = map[string]map[string]string{}
 	}
+	if _, ok := ctx.Hydrate[id]; !ok {
+		ctx.hydrateOrder = append(ctx.hydrateOrder, id)
+	}
 	ctx.Hydrate[id] = props
 }
 
-func Render(i interface{}) (string, *RenderCtx) {
+func Render(i interface{}, opts ...OptionRender) (string, *RenderCtx) {
 	var s strings.Builder
-	var ctx RenderCtx
-	render(&s, &ctx, i)
-	return s.String(), &ctx
+	ctx, _ := RenderTo(&s, i, opts...)
+	return s.String(), ctx
 }
 
-func render(s *strings.Builder, ctx *RenderCtx, c interface{}) {
-	var obj map[string]interface{}
+// renderBufferSize 是 RenderTo 写入 w 前的缓冲大小，避免每个标签都直接写入 w。
+const renderBufferSize = 4096
+
+// RenderTo write html to w, it returns the first error returned by w.
+// If w implements http.Flusher (e.g. http.ResponseWriter), it will be flushed after rendering.
+func RenderTo(w io.Writer, i interface{}, opts ...OptionRender) (*RenderCtx, error) {
+	return renderTo(w, nil, i, opts...)
+}
+
+// renderTo 渲染 i，如果 i 中包含 goja.Value，则需要在持有 vm 时调用。
+func renderTo(w io.Writer, vm *goja.Runtime, i interface{}, opts ...OptionRender) (*RenderCtx, error) {
+	ctx := newRenderCtx(opts...)
+	ctx.vm = vm
+	defer func() {
+		ctx.vm = nil
+	}()
+
+	s := renderWriter{w: w, max: ctx.maxOutputBytes}
+	var bw *bufio.Writer
+	if _, ok := w.(*strings.Builder); !ok {
+		bw = bufio.NewWriterSize(w, renderBufferSize)
+		s.w = bw
+	}
+
+	ctx.root = i
+	render(&s, ctx, i)
+	ctx.root = nil
+	if s.err != nil {
+		return ctx, s.err
+	}
+
+	if bw != nil {
+		err := bw.Flush()
+		if err != nil {
+			return ctx, err
+		}
+	}
+
+	// http.Flusher
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+
+	return ctx, nil
+}
 
+func render(s *renderWriter, ctx *RenderCtx, c interface{}) {
 	switch t := c.(type) {
-	case bool:
+	case nil, bool:
 		// react will not render any Bool type (true and false)
 		return
 	case string:
-		s.WriteString(template.HTMLEscapeString(t))
+		s.WriteString(ctx.escapeText(t))
 		return
-	case []interface{}:
-		for _, c := range t {
-			if c != nil {
-				render(s, ctx, c)
-			}
+	case *Text:
+		s.WriteString(ctx.escapeText(t.Text))
+		return
+	case *lazyNode:
+		render(s, ctx, t.v)
+		return
+	case *goja.Promise:
+		v, err := resolvePromise(ctx.vm, t)
+		if err != nil {
+			s.err = err
+			return
 		}
+		render(s, ctx, v)
 		return
-	case map[string]interface{}:
-		obj = t
-	case VDom:
-		obj = t
+	case goja.Value:
+		renderGojaValue(s, ctx, t)
+		return
+	}
+
+	if forEachItem(c, func(c interface{}) bool {
+		if c != nil {
+			render(s, ctx, c)
+		}
+		return s.err == nil
+	}) {
+		return
+	}
+
+	n, ok := toVNode(c)
+	if !ok {
+		// for literal, e.g. 1, 2, 3
+		s.WriteString(ctx.escapeText(fmt.Sprintf("%v", c)))
+		return
+	}
+
+	renderVNode(s, ctx, &n)
+}
+
+// renderGojaValue 直接渲染 goja.Value，只有在需要时才 Export，而不是 Export 整个树。
+// 这样不再为每个节点创建 map，但是读取属性依然需要 goja.Object.Keys（每个属性约 4 次 alloc），
+// goja 没有更省的遍历方式，它占渲染的大部分 alloc；而一次 RenderCode 的大部分 alloc 来自执行 js，所以总的 alloc 只减少了很少。
+func renderGojaValue(s *renderWriter, ctx *RenderCtx, v goja.Value) {
+	if isNullish(v) {
+		return
+	}
+	if str, ok := gojaString(v); ok {
+		s.WriteString(ctx.escapeText(str))
+		return
+	}
+	o, ok := v.(*goja.Object)
+	if !ok {
+		render(s, ctx, v.Export())
+		return
+	}
+
+	if isPromise(o) {
+		render(s, ctx, o.Export())
+		return
+	}
+
+	switch o.ClassName() {
+	case "Object":
+		n := gojaVNode(o, ctx)
+		renderVNode(s, ctx, &n)
+	case "Array":
+		forEachItem(o, func(c interface{}) bool {
+			render(s, ctx, c)
+			return s.err == nil
+		})
+	case "Function":
+		// react does not render functions
 	default:
-		// for literal, e.g. 1, 2, 3, false
-		s.WriteString(template.HTMLEscapeString(fmt.Sprintf("%v", c)))
+		render(s, ctx, o.Export())
+	}
+}
+
+func renderVNode(s *renderWriter, ctx *RenderCtx, n *vnode) {
+	if err := ctx.countNode(); err != nil {
+		s.err = err
 		return
 	}
 
-	if obj == nil {
+	// for <Head><title>...</title></Head>
+	if n.head {
+		collectHead(s, ctx, n)
 		return
 	}
 
 	// for {{ __dangerousHTML: end_of_header_code }}
-	isHtml := obj["__dangerousHTML"]
-
-	if ht, ok := isHtml.(string); ok {
-		if len(ht) > 0 {
-			s.WriteString(ht)
+	if n.isHTML {
+		if len(n.html) > 0 {
+			s.WriteString(n.html)
 		}
 		return
 	}
 
-	i := obj["nodeName"]
+	nodeName := n.nodeName
+	children := n.children
+	attrs := n.attrs
 
-	nodeName, _ := i.(string)
-	attr := obj["attributes"]
-	var children interface{}
-	var attrMap map[string]interface{}
-	if attr != nil {
-		attrMap = attr.(map[string]interface{})
-		ci := attrMap["children"]
-		if ci != nil {
-			children = ci
+	// for <Counter client:load/>
+	if n.island != nil {
+		island := ctx.addIsland(n.island)
+		attrs = mapAttributes(islandAttributes(island))
+	}
+
+	// for <CDATA>{text}</CDATA>
+	if n.cdata {
+		renderCDATA(s, ctx, textContent(children))
+		return
+	}
+
+	// 使用 RenderPlugin 处理元素，返回的节点会被再次渲染
+	if len(ctx.plugins) != 0 && nodeName != "" && n.island == nil && !n.processed {
+		r, err := applyPlugins(ctx, n)
+		if err != nil {
+			s.err = err
+			return
 		}
+		render(s, ctx, r)
+		return
+	}
+
+	// for <ErrorBoundary fallback={...}>
+	if n.caught != nil {
+		ctx.addError(n.caught)
+	}
+	if n.boundary {
+		renderBoundary(s, ctx, n)
+		return
 	}
 
 	// Fragment 只渲染子节点
 	if nodeName == "" {
-		if children != nil {
-			render(s, ctx, children)
+		if !isNullish(children) {
+			renderChildren(s, ctx, n, children)
 		}
 		return
 	}
 
+	// 用于在 minify 模式中省略结束标签
+	next, parent, siblingKnown := ctx.next, ctx.parent, ctx.siblingKnown
+	ctx.siblingKnown = false
+
+	attrs, children = formElementProps(ctx, n, attrs, children)
+	if nodeName == "select" {
+		v, _ := controlledAttribute(n.attrs, "value", "defaultValue")
+		prev := ctx.form
+		ctx.form = formState{selectValue: v, inSelect: true}
+		defer func() {
+			ctx.form = prev
+		}()
+	}
+
+	ns := elementNamespace(ctx.ns, nodeName)
+	foreign := ns == svgNamespace || ns == mathNamespace
+	dangerouslySetInnerHTML, _ := n.attr("dangerouslySetInnerHTML")
+	html, hasHTML := dangerouslySetInnerHTML.(map[string]interface{})
+	empty := !hasHTML && isEmptyChildren(children)
+
 	selfclose := false
-	switch nodeName {
-	// Omitted close tags
-	case "input":
-		selfclose = true
-	case "area", "base", "br", "col", "embed", "hr", "img", "keygen", "link", "meta", "param", "source", "track", "wbr":
+	if ctx.xml || foreign {
+		// 在 xml 与 svg、math 中，没有子节点的元素自闭合
+		selfclose = empty
+	} else if voidElements[nodeName] {
+		// Omitted close tags
 		selfclose = true
-	case "html":
+	}
+	if nodeName == "html" && !ctx.xml && !foreign {
 		s.WriteString("<!DOCTYPE html>")
+		if ctx.prettyPrint() {
+			s.WriteString("\n")
+		}
 	}
 
 	s.WriteString("<")
 	s.WriteString(nodeName)
-	if attr != nil {
-		renderAttributes(s, ctx, attrMap)
+	if ctx.xml {
+		if xmlns := declareNamespace(s, ctx, n, ns); xmlns != "" {
+			prev := ctx.xmlns
+			ctx.xmlns = xmlns
+			defer func() {
+				ctx.xmlns = prev
+			}()
+		}
+	}
+	minifyTag := ctx.minify && !ctx.xml
+	if minifyTag {
+		var b strings.Builder
+		renderAttributes(&b, ctx, attrs)
+		a := minifyAttributes(b.String())
+		s.WriteString(a)
+		if selfclose && foreign && a != "" && !strings.HasSuffix(a, `"`) {
+			// <circle r=1 /> 避免 / 被当作属性值的一部分
+			s.WriteString(" ")
+		}
+	} else {
+		renderAttributes(s, ctx, attrs)
 	}
 
 	if selfclose {
-		s.WriteString("/>")
+		if minifyTag && !foreign {
+			s.WriteString(">")
+		} else {
+			s.WriteString("/>")
+		}
 		// 自闭合标签没有 children
 		return
 	}
 
 	s.WriteString(">")
-	html, ok := lookupMap[map[string]interface{}](attrMap, "dangerouslySetInnerHTML")
-	if ok {
+
+	prevNs := ctx.ns
+	ctx.ns = childrenNamespace(ns, nodeName)
+	if whitespaceSensitive[nodeName] {
+		ctx.preserve++
+	}
+	if hasHTML {
 		h, ok := lookupMap[string](html, "__html")
 		if ok {
 			s.WriteString(h)
 		} else {
 			render(s, ctx, html)
 		}
-	} else {
-		if children != nil {
-			render(s, ctx, children)
+	} else if nodeName == "head" && !ctx.headRendered {
+		renderHead(s, ctx, children)
+	} else if !isNullish(children) {
+		// for <script>{code}</script>
+		if escape := rawTextEscaper(n); escape != nil && !ctx.xml && !foreign {
+			renderRawText(s, ctx, n, children, escape)
+		} else {
+			renderChildren(s, ctx, n, children)
+		}
+	}
+	if whitespaceSensitive[nodeName] {
+		ctx.preserve--
+	}
+	ctx.ns = prevNs
+
+	if nodeName == "body" && ctx.injectHydrationScript {
+		if script := ctx.HydrationScript(); script != "" {
+			s.WriteString("<script>")
+			s.WriteString(script)
+			s.WriteString("</script>")
 		}
 	}
 
-	s.WriteString(fmt.Sprintf("</%v>", nodeName))
+	if minifyTag && !foreign && siblingKnown && omitEndTag(nodeName, next, parent) {
+		return
+	}
 
+	s.WriteString("</")
+	s.WriteString(nodeName)
+	s.WriteString(">")
 }
 
 func lookupMapI(m interface{}, keys ...string) (interface{}, bool) {
@@ -1,11 +1,15 @@
 package gojsx
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	_ "embed"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
@@ -34,7 +38,7 @@ func TestJsx(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, "<!DOCTYPE html><html lang=\"zh\"><head><meta charSet=\"UTF-8\"/><title>UnTitled</title><link href=\"https://unpkg.com/tailwindcss@^2/dist/tailwind.min.css\" rel=\"stylesheet\"/></head><body><div b=\"1\" c=\"1.1\"></div><div class=\"bg-red-50 border-black text-black\">a /2<div b=\"2\" class=\"form\" style=\"font-size: 1px; padding: 2px;\"> f <ul><li> 1 </li><li> 2 </li><li> 3 </li><li> 4 </li></ul> x:2c: c1</div><img alt=\"asdfsf&#34;12312\" data-x=\"{&#34;a&#34;:&#34;`&#39;&#34;}\" src=\"a.jpb\"/><p>&lt;h1&gt;dangerouslySetInnerHTML&lt;/h1&gt;</p><p><h1>dangerouslySetInnerHTML</h1></p></div><button class=\"btn btn-square btn-xs\"><svg class=\"h-6 w-6\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\" xmlns=\"http://www.w3.org/2000/svg\"><path d=\"M21 21l-6-6m2-5a7 7 0 11-14 0 7 7 0 0114 0z\" stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\"></path></svg></button></body></html>", s)
+	assert.Equal(t, "<!DOCTYPE html><html lang=\"zh\"><head><meta charSet=\"UTF-8\"/><title>UnTitled</title><link href=\"https://unpkg.com/tailwindcss@^2/dist/tailwind.min.css\" rel=\"stylesheet\"/></head><body><div b=\"1\" c=\"1.1\"></div><div class=\"bg-red-50 border-black text-black\">a /2<div b=\"2\" class=\"form\" style=\"font-size: 1px; padding: 2px;\"> f <ul><li> 1 </li><li> 2 </li><li> 3 </li><li> 4 </li></ul> x:2c: c1</div><img src=\"a.jpb\" alt=\"asdfsf&#34;12312\" data-x=\"{&#34;a&#34;:&#34;`&#39;&#34;}\"/><p>&lt;h1&gt;dangerouslySetInnerHTML&lt;/h1&gt;</p><p><h1>dangerouslySetInnerHTML</h1></p></div><button class=\"btn btn-square btn-xs\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-6 w-6\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M21 21l-6-6m2-5a7 7 0 11-14 0 7 7 0 0114 0z\"/></svg></button></body></html>", s)
 }
 
 //go:embed test/blog/tailwind.css
@@ -284,7 +288,7 @@ func TestHydrate(t *testing.T) {
 
 func TestRenderAttributes(t *testing.T) {
 	var s strings.Builder
-	renderAttributes(&s, &RenderCtx{}, map[string]interface{}{
+	renderAttributes(&s, &RenderCtx{}, mapAttributes(map[string]interface{}{
 		"tabIndex":   1,
 		"autoFocus":  "true",
 		"default":    true,
@@ -293,9 +297,9 @@ func TestRenderAttributes(t *testing.T) {
 		"data-abc":   "abc",
 		"data-empty": "",
 		"data-bool":  false,
-	})
+	}))
 
-	assert.Equal(t, ` autofocus data-abc="abc" data-bool="false" default disabled tabIndex="1"`, s.String())
+	assert.Equal(t, ` autofocus="" data-abc="abc" data-bool="false" data-empty="" default="" disabled="" tabindex="1"`, s.String())
 }
 
 func TestRenderStyle(t *testing.T) {
@@ -309,17 +313,53 @@ func TestRenderStyle(t *testing.T) {
 	})
 
 	assert.Equal(t, "---color: #EEE; --color: #EEE; a-color: #EEE; color: #fff; font-width: 100;", s.String())
+
+	s.Reset()
+	renderStyle(&s, map[string]interface{}{
+		"width":           10,
+		"height":          1.5,
+		"margin":          0,
+		"opacity":         0.5,
+		"zIndex":          int64(2),
+		"WebkitLineClamp": 3,
+		"--gap":           4,
+		"--mainColor":     "red",
+		"color":           nil,
+		"display":         false,
+		"padding":         "",
+		"fontFamily":      `"Helvetica" <b>`,
+	})
+
+	assert.Equal(t, "--gap: 4; --mainColor: red; -webkit-line-clamp: 3; font-family: &#34;Helvetica&#34; &lt;b&gt;; height: 1.5px; margin: 0; opacity: 0.5; width: 10px; z-index: 2;", s.String())
+}
+
+func TestRenderStyleJsx(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`export default () => <div>
+	<p style={{width: 10, lineHeight: 1.5, color: null, background: undefined, '--x': 1}}></p>
+	<p style={{color: null}}></p>
+</div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<div><p style="width: 10px; line-height: 1.5; --x: 1;"></p><p></p></div>`, s)
 }
 
 func TestHyphenateStyleName(t *testing.T) {
 	cases := map[string]string{
-		"fontWidth":     "font-width",
-		"FontWidth":     "font-width",
-		"color":         "color",
-		"Color":         "color",
-		"--color":       "--color",
-		"MozTransition": "-moz-transition",
-		"msTransition":  "-ms-transition",
+		"fontWidth":       "font-width",
+		"FontWidth":       "font-width",
+		"color":           "color",
+		"Color":           "color",
+		"--color":         "--color",
+		"MozTransition":   "-moz-transition",
+		"msTransition":    "-ms-transition",
+		"WebkitLineClamp": "-webkit-line-clamp",
 	}
 
 	for in, out := range cases {
@@ -427,3 +467,249 @@ export default function Index(props) {
 	}
 
 }
+
+type errWriter struct {
+	n int
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.n+len(p) > 10 {
+		return 0, errors.New("write limit")
+	}
+	e.n += len(p)
+	return len(p), nil
+}
+
+func TestRenderTo(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := []byte(`export default (props) => <ul>{props.li.map(i => <li>{i}</li>)}</ul>`)
+	li := make([]int, 2000)
+	for i := range li {
+		li[i] = i
+	}
+	props := map[string]interface{}{"li": li}
+
+	s, _, err := j.RenderCode(code, props)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	_, err = j.RenderCodeTo(&b, code, props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s, b.String())
+
+	rec := httptest.NewRecorder()
+	_, err = j.RenderCodeTo(rec, code, props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, s, rec.Body.String())
+
+	_, err = j.RenderCodeTo(&errWriter{}, code, props)
+	assert.EqualError(t, err, "write limit")
+}
+
+func TestExecContext(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, _, err = j.RenderCodeContext(ctx, []byte(`export default () => { while (true) {} }`), nil)
+	var te *TimeoutError
+	if assert.ErrorAs(t, err, &te) {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	// the interrupted vm is discarded, the pool still works
+	s, _, err := j.RenderCode([]byte(`export default () => <p>ok</p>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>ok</p>", s)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = j.ExecCodeContext(ctx, []byte(`export default 1`))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAsyncComponent(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+async function loadTitle(id) {
+	return "post " + id
+}
+
+async function Post(props) {
+	const title = await loadTitle(props.id)
+	return <h1>{title}</h1>
+}
+
+export default async function Page(props) {
+	await null
+	return <div>{props.ids.map(id => <Post id={id}></Post>)}</div>
+}
+`), map[string]interface{}{"ids": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<div><h1>post 1</h1><h1>post 2</h1></div>", s)
+
+	_, _, err = j.RenderCode([]byte(`
+async function Post(props) {
+	throw new Error("load failed")
+}
+export default () => <div><Post></Post></div>
+`), nil)
+	assert.ErrorContains(t, err, "Error: load failed")
+
+	_, _, err = j.RenderCode([]byte(`export default () => <div>{new Promise(() => {})}</div>`), nil)
+	assert.ErrorIs(t, err, ErrPromisePending)
+}
+
+func TestHydrateIdPerRender(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := []byte(`export default () => <div><p hydrate-a="1"></p><p></p><p hydrate-b="2"></p></div>`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			s, ctx, err := j.RenderCode(code, nil, WithHydrateIdPrefix("h-"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			assert.Equal(t, `<div><p data-hydrate="h-0"></p><p></p><p data-hydrate="h-1"></p></div>`, s)
+			assert.Equal(t, map[string]map[string]string{
+				"h-0": {"hydrate-a": "1"},
+				"h-1": {"hydrate-b": "2"},
+			}, ctx.Hydrate)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHydrationScript(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ctx, err := j.RenderCode([]byte(`
+function Counter(props) {
+	return <button hydrate-onclick="function(){ this.hydrate.count++; this.textContent = this.hydrate.count }" hydrate-count={JSON.stringify(props.count)}>{props.count}</button>
+}
+export default (props) => <html><body><Counter count={1}></Counter><p hydrateOnDoubleClick="() => alert('</script>')"></p></body></html>
+`), nil, WithHydrationScript(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := `(function(){var h={"0":function(el){el.addEventListener("click",(function(){ this.hydrate.count++; this.textContent = this.hydrate.count }));el.hydrate={"count":(1)};},"1":function(el){el.addEventListener("dblclick",(() => alert('<\/script>')));}};for(var id in h){var el=document.querySelector('[data-hydrate="'+id+'"]');if(el){h[id](el)}}})();`
+	assert.Equal(t, script, ctx.HydrationScript())
+	assert.Equal(t, `<!DOCTYPE html><html><body><button data-hydrate="0">1</button><p data-hydrate="1"></p><script>`+script+`</script></body></html>`, s)
+}
+
+func TestHead(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ctx, err := j.RenderCode([]byte(`
+import {Head} from "gojsx"
+
+function Detail(props) {
+	return <article>
+		<Head>
+			<title>{props.title}</title>
+			<meta name="description" content="post"/>
+			<link rel="canonical" href="https://example.com/post"/>
+		</Head>
+		<h1>{props.title}</h1>
+	</article>
+}
+
+export default (props) => <html>
+	<head>
+		<meta charSet="UTF-8"/>
+		<title>Default</title>
+		<Head><meta name="description" content="default"/></Head>
+	</head>
+	<body><Detail title={props.title}></Detail></body>
+</html>
+`), map[string]interface{}{"title": "Post"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<!DOCTYPE html><html><head><meta charSet="UTF-8"/><title>Post</title><meta name="description" content="post"/><link rel="canonical" href="https://example.com/post"/></head><body><article><h1>Post</h1></article></body></html>`, s)
+	assert.Equal(t, []string{`<meta charSet="UTF-8"/>`, `<title>Post</title>`, `<meta name="description" content="post"/>`, `<link rel="canonical" href="https://example.com/post"/>`}, ctx.Head)
+
+	// without <head>
+	s, ctx, err = j.RenderCode([]byte(`
+import {Head} from "react/jsx-runtime"
+export default () => <div><Head><title>A</title><meta key="k" name="a"/></Head><Head><title>B</title><meta key="k" name="b"/></Head></div>
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div></div>`, s)
+	assert.Equal(t, []string{`<title>B</title>`, `<meta name="b"/>`}, ctx.Head)
+
+	// <Head> in async components
+	s, ctx, err = j.RenderCode([]byte(`
+import {Head} from "gojsx"
+async function Title() {
+	const title = await Promise.resolve("Async")
+	return <Head><title>{title}</title></Head>
+}
+async function Detail() {
+	await null
+	return <article><Head>{Promise.resolve(<meta name="description" content="async"/>)}</Head><Title/></article>
+}
+export default () => <html><head><title>Default</title></head><body><Detail/></body></html>
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<!DOCTYPE html><html><head><title>Async</title><meta name="description" content="async"/></head><body><article></article></body></html>`, s)
+	assert.Equal(t, []string{`<title>Async</title>`, `<meta name="description" content="async"/>`}, ctx.Head)
+
+	// async 组件在 <head> 与 <Head> 中 reject 时返回错误
+	for _, code := range []string{
+		`export default () => <html><head><Bad/></head><body></body></html>`,
+		`export default () => <html><head></head><body><Head><Bad/></Head></body></html>`,
+		`export default () => <html><head></head><body><Head><title>{Promise.resolve(<Bad/>)}</title></Head></body></html>`,
+		`export default () => <div><Head><Bad/></Head></div>`,
+	} {
+		_, _, err = j.RenderCode([]byte(`
+import {Head} from "gojsx"
+async function Bad() { throw new Error("head boom") }
+`+code), nil)
+		assert.ErrorContains(t, err, "head boom", code)
+	}
+}
@@ -0,0 +1,121 @@
+package gojsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dop251/goja"
+	"math"
+	"time"
+)
+
+// Limits 限制一次执行（包括渲染）使用的资源，用于执行不受信任的代码，0 表示不限制。
+type Limits struct {
+	// MaxCallStackSize 是 js 调用栈的最大深度，如无限递归的组件
+	MaxCallStackSize int
+	// Timeout 是一次执行（包括渲染）的最长时间
+	Timeout time.Duration
+	// MaxOutputBytes 是渲染输出的最大字节数
+	MaxOutputBytes int64
+	// MaxNodes 是渲染的元素的最大数量
+	MaxNodes int
+}
+
+// LimitKind 是超出的限制
+type LimitKind string
+
+const (
+	LimitCallStack   LimitKind = "call stack size"
+	LimitTimeout     LimitKind = "execution time"
+	LimitOutputBytes LimitKind = "output bytes"
+	LimitNodes       LimitKind = "node count"
+)
+
+// LimitError is returned when the execution exceeds one of the Limits.
+type LimitError struct {
+	Kind LimitKind
+	// Limit 是超出的限制的值，LimitTimeout 的单位是纳秒（time.Duration）
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	limit := fmt.Sprint(e.Limit)
+	if e.Kind == LimitTimeout {
+		limit = time.Duration(e.Limit).String()
+	}
+	return fmt.Sprintf("%s limit exceeded: %s", e.Kind, limit)
+}
+
+type limitsOption Limits
+
+func (l limitsOption) applyRenderOptions(options *renderOptions) {
+	limits := Limits(l)
+	options.Limits = &limits
+}
+
+func (l limitsOption) applyRunOptions(options *execOptions) {
+	limits := Limits(l)
+	options.Limits = &limits
+	options.RenderOptions = append(options.RenderOptions, l)
+}
+
+// WithLimits 为本次执行指定 Limits，替换 Option.Limits。
+func WithLimits(l Limits) interface {
+	OptionExec
+	OptionRender
+} {
+	return limitsOption(l)
+}
+
+// renderContextOption 使渲染在 ctx 结束时停止，ctx 是执行的 context
+type renderContextOption struct {
+	ctx context.Context
+}
+
+func (r renderContextOption) applyRenderOptions(options *renderOptions) {
+	options.ctx = r.ctx
+}
+
+// limitContext 为 ctx 添加 Timeout 限制
+func (l Limits) limitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// setCallStackSize 设置 vm 的最大调用栈深度，0 表示恢复为不限制（goja 的默认值）
+func setCallStackSize(vm *goja.Runtime, size int) {
+	if size <= 0 {
+		size = math.MaxInt32
+	}
+	vm.SetMaxCallStackSize(size)
+}
+
+// limitError 将超出限制导致的错误转换为 *LimitError，parent 是添加 Timeout 之前的 ctx
+func (l Limits) limitError(parent context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var so *goja.StackOverflowError
+	if errors.As(err, &so) {
+		return &LimitError{Kind: LimitCallStack, Limit: int64(l.MaxCallStackSize)}
+	}
+	var te *TimeoutError
+	if l.Timeout > 0 && errors.As(err, &te) && errors.Is(te.Err, context.DeadlineExceeded) && parent.Err() == nil {
+		return &LimitError{Kind: LimitTimeout, Limit: int64(l.Timeout)}
+	}
+	return err
+}
+
+// countNode 记录渲染的元素数量，超出 MaxNodes 或者执行被中断时返回错误
+func (ctx *RenderCtx) countNode() error {
+	ctx.nodes++
+	if ctx.maxNodes > 0 && ctx.nodes > ctx.maxNodes {
+		return &LimitError{Kind: LimitNodes, Limit: int64(ctx.maxNodes)}
+	}
+	if ctx.execCtx != nil && ctx.execCtx.Err() != nil {
+		return &TimeoutError{Err: ctx.execCtx.Err()}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package gojsx
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 1, Limits: Limits{MaxCallStackSize: 200, Timeout: time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var le *LimitError
+	_, _, err = j.RenderCode([]byte(`
+const Tree = ({depth}) => <div><Tree depth={depth + 1}/></div>
+export default () => <Tree depth={0}/>`), nil)
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitCallStack, le.Kind)
+		assert.Equal(t, int64(200), le.Limit)
+	}
+
+	_, err = j.ExecCode([]byte(`while (true) {}`), WithLimits(Limits{Timeout: 50 * time.Millisecond}))
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitTimeout, le.Kind)
+		assert.Equal(t, "execution time limit exceeded: 50ms", le.Error())
+	}
+
+	// 调用者的 ctx 结束时依然返回 TimeoutError
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = j.ExecCodeContext(ctx, []byte(`while (true) {}`))
+	var te *TimeoutError
+	assert.ErrorAs(t, err, &te)
+
+	list := []byte(`export default () => <ul>{Array.from({length: 100}, (_, i) => <li>{i}</li>)}</ul>`)
+	_, err = j.RenderCodeTo(io.Discard, list, nil, WithLimits(Limits{MaxOutputBytes: 100}))
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitOutputBytes, le.Kind)
+	}
+	_, err = j.RenderCodeTo(io.Discard, list, nil, WithLimits(Limits{MaxNodes: 50}))
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitNodes, le.Kind)
+	}
+
+	// 没有超出限制，vm 可以继续使用
+	var b strings.Builder
+	_, err = j.RenderCodeTo(&b, list, nil, WithLimits(Limits{MaxNodes: 101, MaxOutputBytes: int64(len("<ul></ul>") + 100*len("<li></li>") + 190)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(b.String(), "<ul><li>0</li>"))
+
+	_, err = RenderTo(io.Discard, []interface{}{&Element{NodeName: "p"}, &Element{NodeName: "p"}}, WithLimits(Limits{MaxNodes: 1}))
+	assert.ErrorAs(t, err, &le)
+}
+
+func TestLimitsCallable(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 1, Limits: Limits{MaxCallStackSize: 200, Timeout: 50 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	ex, err := j.ExecCode([]byte(`
+export function loop() { while (true) {} }
+export function deep(n) { return deep(n + 1) }
+export default (n) => n + 1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 执行结束之后调用的导出的函数同样受 Limits 限制
+	var le *LimitError
+	_, err = ex.Exports["loop"].(Callable)()
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitTimeout, le.Kind)
+	}
+	_, err = ex.Exports["deep"].(Callable)(0)
+	if assert.ErrorAs(t, err, &le) {
+		assert.Equal(t, LimitCallStack, le.Kind)
+	}
+
+	// vm 可以继续使用
+	v, err := ex.Default.(Callable)(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), v.ToInteger())
+	ex, err = j.ExecCode([]byte(`export default 1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Any{int64(1)}, ex.Default)
+}
@@ -0,0 +1,226 @@
+package gojsx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+	"github.com/zbysir/gojsx/pkg/sourcemap"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdown 转换为 jsx 之后，需要生成 sourcemap 才能让运行时的报错指向 .md/.mdx 中的行。
+// esbuild 会读取输入代码中内联的 sourcemap，并合并到输出的 sourcemap 中。
+//
+// html 是由 goldmark 渲染的，无法直接知道每个块在输出中的位置，所以在渲染之前在每个顶层块之前插入 lineMarker，
+// 渲染之后再从输出中找到并删除它们。只映射到行：块中的第 n 行对应源码中块的第 n 行。
+
+var lineMarkerKind = ast.NewNodeKind("GojsxLineMarker")
+
+type lineMarker struct {
+	ast.BaseBlock
+	index int
+}
+
+func (l *lineMarker) Kind() ast.NodeKind {
+	return lineMarkerKind
+}
+
+func (l *lineMarker) Dump(source []byte, level int) {
+	ast.DumpHelper(l, source, level, nil, nil)
+}
+
+func lineMarkerText(index int) string {
+	return fmt.Sprintf("GOJSXLINE_%d_ENILXSJOG", index)
+}
+
+var lineMarkerRegexp = regexp.MustCompile(`GOJSXLINE_(\d+)_ENILXSJOG`)
+
+type lineMarkerRenderer struct{}
+
+func (lineMarkerRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(lineMarkerKind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString(lineMarkerText(n.(*lineMarker).index))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// blockLines 是一个顶层块在源码中的行范围（从 0 开始）
+type blockLines struct {
+	start, end int
+}
+
+// insertLineMarkers 在每个顶层块之前插入 lineMarker，返回每个 lineMarker 对应的源码行
+func insertLineMarkers(doc ast.Node, src []byte) []blockLines {
+	var blocks []blockLines
+	for c := doc.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		start, end, ok := nodeSegments(c)
+		if ok {
+			doc.InsertBefore(doc, c, &lineMarker{index: len(blocks)})
+			blocks = append(blocks, blockLines{
+				start: bytes.Count(src[:start], []byte("\n")),
+				end:   bytes.Count(src[:end], []byte("\n")),
+			})
+		}
+		c = next
+	}
+	return blocks
+}
+
+// removeLineMarkers 删除 lineMarker，避免影响 markdownExport 等之后对 ast 的处理
+func removeLineMarkers(doc ast.Node) {
+	for c := doc.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		if c.Kind() == lineMarkerKind {
+			doc.RemoveChild(doc, c)
+		}
+		c = next
+	}
+}
+
+// nodeSegments 返回节点（包括子节点）在源码中的起止位置，如列表等容器节点本身没有 Lines
+func nodeSegments(n ast.Node) (start, end int, ok bool) {
+	start = -1
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		lines := n.Lines()
+		if lines == nil || lines.Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+		if start == -1 || lines.At(0).Start < start {
+			start = lines.At(0).Start
+		}
+		if s := lines.At(lines.Len() - 1).Stop; s > end {
+			end = s
+		}
+		return ast.WalkContinue, nil
+	})
+	if start == -1 {
+		return 0, 0, false
+	}
+	// Stop 可能在行末的换行符之后
+	if end > start {
+		end--
+	}
+	return start, end, true
+}
+
+// markdownSourceMap 记录 markdown 生成的 jsx 代码与源码的行的对应关系
+type markdownSourceMap struct {
+	m     sourcemap.Map
+	file  string
+	lines []string
+	// line 是正在生成的 jsx 代码的行号
+	line int
+}
+
+func newMarkdownSourceMap(file string, src []byte) *markdownSourceMap {
+	return &markdownSourceMap{
+		m:     sourcemap.Map{File: file, SourcesContent: []string{string(src)}},
+		file:  file,
+		lines: strings.Split(string(src), "\n"),
+	}
+}
+
+// writeCode 写入逐行对应源码的 js 代码，sourceLines 是每一行在源码中的行号，-1 表示没有对应的行。
+// js 代码的每一行都是源码中的一行（可能去掉了开头的空白），所以可以映射到列：在每个 token 的开始添加 mapping。
+func (s *markdownSourceMap) writeCode(w *bytes.Buffer, code string, sourceLines []int) {
+	codeLines := strings.Split(code, "\n")
+	for i, src := range sourceLines {
+		if src < 0 || i >= len(codeLines) || src >= len(s.lines) {
+			continue
+		}
+		line := codeLines[i]
+		offset := 0
+		if strings.HasSuffix(s.lines[src], line) {
+			offset = len(s.lines[src]) - len(line)
+		}
+		// sourcemap 中的列以 UTF-16 编码单元计算
+		for _, c := range tokenStarts(line) {
+			s.m.AddMapping(&sourcemap.Mapping{
+				GeneratedLine:   s.line + i,
+				GeneratedColumn: utf16Column(line, c),
+				OriginalFile:    s.file,
+				OriginalLine:    src,
+				OriginalColumn:  utf16Column(s.lines[src], c+offset),
+			})
+		}
+	}
+	s.write(w, code)
+}
+
+// tokenStarts 返回每个 token（标识符、数字或者符号）开始的列，总是包含 0
+func tokenStarts(line string) []int {
+	starts := []int{0}
+	isWord := func(c byte) bool {
+		return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	for i := 1; i < len(line); i++ {
+		c := line[i]
+		if c == ' ' || c == '\t' {
+			continue
+		}
+		if !isWord(c) || !isWord(line[i-1]) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// writeHtml 写入插入了 lineMarker 的 jsx，删除 lineMarker 并记录块中每一行对应的源码行
+func (s *markdownSourceMap) writeHtml(w *bytes.Buffer, html []byte, blocks []blockLines) {
+	block := -1
+	blockStart := 0
+	for i, line := range bytes.Split(html, []byte("\n")) {
+		if i != 0 {
+			w.WriteByte('\n')
+			s.line++
+		}
+		if m := lineMarkerRegexp.FindSubmatch(line); m != nil {
+			index, _ := strconv.Atoi(string(m[1]))
+			if index < len(blocks) {
+				block = index
+				blockStart = s.line
+			}
+			line = lineMarkerRegexp.ReplaceAll(line, nil)
+		}
+		if block != -1 {
+			b := blocks[block]
+			src := b.start + s.line - blockStart
+			if src > b.end {
+				src = b.end
+			}
+			s.addLine(s.line, src)
+		}
+		w.Write(line)
+	}
+}
+
+func (s *markdownSourceMap) write(w *bytes.Buffer, code string) {
+	w.WriteString(code)
+	s.line += bytes.Count([]byte(code), []byte("\n"))
+}
+
+func (s *markdownSourceMap) addLine(generated, original int) {
+	s.m.AddMapping(&sourcemap.Mapping{
+		GeneratedLine: generated,
+		OriginalFile:  s.file,
+		OriginalLine:  original,
+	})
+}
+
+// inline 返回内联的 sourcemap 注释
+func (s *markdownSourceMap) inline() (string, error) {
+	if s.m.Len() == 0 {
+		return "", nil
+	}
+	return s.m.Inline()
+}
@@ -0,0 +1,107 @@
+package gojsx
+
+import (
+	"strings"
+)
+
+const (
+	htmlNamespace = "http://www.w3.org/1999/xhtml"
+	svgNamespace  = "http://www.w3.org/2000/svg"
+	mathNamespace = "http://www.w3.org/1998/Math/MathML"
+)
+
+// voidElements 是 html 中没有结束标签的元素
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"keygen": true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// elementNamespace 返回元素的命名空间，parent 是父元素中子节点的命名空间，为空表示 html
+func elementNamespace(parent, nodeName string) string {
+	switch nodeName {
+	case "svg":
+		return svgNamespace
+	case "math":
+		return mathNamespace
+	}
+	if parent == "" {
+		return htmlNamespace
+	}
+	return parent
+}
+
+// childrenNamespace 返回子节点的命名空间，<foreignObject> 中的子节点是 html
+func childrenNamespace(ns, nodeName string) string {
+	if ns == svgNamespace && nodeName == "foreignObject" {
+		return htmlNamespace
+	}
+	return ns
+}
+
+// declareNamespace 在 xml 模式中为根 <svg>、<math>、<html> 以及 <foreignObject> 中的 html 元素写入 xmlns，返回新声明的命名空间。
+func declareNamespace(s *renderWriter, ctx *RenderCtx, n *vnode, ns string) string {
+	if v, ok := n.attr("xmlns"); ok {
+		if v, ok := v.(string); ok {
+			return v
+		}
+	}
+	if ns == ctx.xmlns {
+		return ""
+	}
+
+	switch {
+	case ns == svgNamespace || ns == mathNamespace:
+	case ns == htmlNamespace && (n.nodeName == "html" || ctx.xmlns != ""):
+	default:
+		return ""
+	}
+
+	s.WriteString(` xmlns="`)
+	s.WriteString(ns)
+	s.WriteString(`"`)
+	return ns
+}
+
+// isEmptyChildren 判断元素是否没有需要渲染的子节点
+func isEmptyChildren(c interface{}) bool {
+	if isNullish(c) {
+		return true
+	}
+	if _, ok := c.(bool); ok {
+		return true
+	}
+	empty := true
+	if forEachItem(c, func(i interface{}) bool {
+		empty = isEmptyChildren(i)
+		return empty
+	}) {
+		return empty
+	}
+	return false
+}
+
+// renderCDATA 在 xml 与 svg、math 中渲染 CDATA，在 html 中 CDATA 无效，渲染为转义后的文本。
+func renderCDATA(s *renderWriter, ctx *RenderCtx, text string) {
+	if !ctx.xml && ctx.ns != svgNamespace && ctx.ns != mathNamespace {
+		s.WriteString(ctx.escapeText(text))
+		return
+	}
+
+	s.WriteString("<![CDATA[")
+	// ]]> 会结束 CDATA，拆分到两个 CDATA 中
+	s.WriteString(strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>"))
+	s.WriteString("]]>")
+}
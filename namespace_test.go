@@ -0,0 +1,90 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSvgNamespace(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+import {CDATA} from "gojsx"
+export default () => <div>
+	<svg viewBox="0 0 10 10">
+		<circle cx={5} cy={5} r={4}/>
+		<style><CDATA>{"circle > a { fill: red }"}</CDATA></style>
+		<foreignObject><p>a<br/></p><input/></foreignObject>
+	</svg>
+	<math><mi>x</mi><mspace/></math>
+	<p><CDATA>{"a<b"}</CDATA></p>
+	<style>{"a > b {}"}</style>
+</div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<div>`+
+		`<svg viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/><style><![CDATA[circle > a { fill: red }]]></style><foreignObject><p>a<br/></p><input/></foreignObject></svg>`+
+		`<math><mi>x</mi><mspace/></math>`+
+		`<p>a&lt;b</p>`+
+		`<style>a > b {}</style>`+
+		`</div>`, s)
+}
+
+func TestXML(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rss
+	s, _, err := j.RenderCode([]byte(`
+import {CDATA} from "gojsx"
+export default (props) => <rss version="2.0">
+	<channel>
+		<title>Blog</title>
+		<link>https://example.com</link>
+		{props.posts.map(p => <item>
+			<title>{p.title}</title>
+			<description><CDATA>{p.content}</CDATA></description>
+			<guid isPermaLink="false">{p.id}</guid>
+			<category/>
+		</item>)}
+	</channel>
+</rss>`), map[string]interface{}{
+		"posts": []map[string]interface{}{
+			{"id": 1, "title": "A & B", "content": "<p>a]]>b</p>"},
+		},
+	}, WithXML(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<rss version="2.0"><channel><title>Blog</title><link>https://example.com</link>`+
+		`<item><title>A &amp; B</title><description><![CDATA[<p>a]]]]><![CDATA[>b</p>]]></description><guid isPermaLink="false">1</guid><category/></item>`+
+		`</channel></rss>`, s)
+
+	// xhtml
+	s, _, err = j.RenderCode([]byte(`export default () => <html>
+	<head><meta charSet="UTF-8"/><script>{"a < b"}</script></head>
+	<body><svg><foreignObject><div></div></foreignObject></svg><br/></body>
+</html>`), nil, WithXML(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<html xmlns="http://www.w3.org/1999/xhtml"><head><meta charSet="UTF-8"/><script>a &lt; b</script></head>`+
+		`<body><svg xmlns="http://www.w3.org/2000/svg"><foreignObject><div xmlns="http://www.w3.org/1999/xhtml"/></foreignObject></svg><br/></body></html>`, s)
+
+	// standalone svg
+	s, _, err = j.RenderCode([]byte(`export default () => <svg width={10}><rect width={10} height={10}/></svg>`), nil, WithXML(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="10"><rect width="10" height="10"/></svg>`, s)
+}
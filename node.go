@@ -0,0 +1,280 @@
+package gojsx
+
+import (
+	"fmt"
+	"github.com/dop251/goja"
+)
+
+// Node 是有类型的 VDom 节点，可以是 *Element、*Text、*Fragment、*RawHTML。
+// 使用 ToNode 将 VDom 转为 Node，使用 Walk、Transform 检查与修改节点树，Node 可以直接传给 Render 渲染。
+type Node interface {
+	node()
+}
+
+// Attribute 是元素的一个属性
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Element 是一个 html 元素，如 <div class="a"></div>
+type Element struct {
+	NodeName string
+	// Attributes 保持源码中的顺序，不包含 children
+	Attributes []Attribute
+	Children   []Node
+	Key        interface{}
+
+	// island 是 <Counter client:load/> 的信息
+	island map[string]interface{}
+	// processed 为 true 时表示已经被 RenderPlugin 处理过，不会再次处理
+	processed bool
+}
+
+// Text 是文本节点，渲染时会被转义
+type Text struct {
+	Text string
+}
+
+// Fragment 只渲染子节点
+type Fragment struct {
+	Children []Node
+
+	// for <Head> and <CDATA>
+	head  bool
+	cdata bool
+	// caught 是 <ErrorBoundary> 捕获的错误
+	caught map[string]interface{}
+	// boundary 与 fallback 是没有捕获到错误的 <ErrorBoundary>，见 vnode.boundary
+	boundary bool
+	fallback interface{}
+}
+
+// RawHTML 是不会被转义的 html，如 {__dangerousHTML: html}
+type RawHTML struct {
+	HTML string
+}
+
+func (*Element) node()  {}
+func (*Text) node()     {}
+func (*Fragment) node() {}
+func (*RawHTML) node()  {}
+
+// Attr 返回属性值
+func (e *Element) Attr(key string) (interface{}, bool) {
+	for _, a := range e.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetAttr 设置属性值，如果属性不存在则添加到最后
+func (e *Element) SetAttr(key string, value interface{}) {
+	for i, a := range e.Attributes {
+		if a.Key == key {
+			e.Attributes[i].Value = value
+			return
+		}
+	}
+	e.Attributes = append(e.Attributes, Attribute{Key: key, Value: value})
+}
+
+// RemoveAttr 删除属性
+func (e *Element) RemoveAttr(key string) {
+	for i, a := range e.Attributes {
+		if a.Key == key {
+			e.Attributes = append(e.Attributes[:i], e.Attributes[i+1:]...)
+			return
+		}
+	}
+}
+
+// ToNode 将 VDom（或者 Export 出来的任意值，如数组、字符串）转为 Node，不会被渲染的值（如 null、bool、函数）返回 nil。
+func ToNode(i interface{}) Node {
+	n, _ := toNode(i, settledPromise)
+	return n
+}
+
+// settledPromise 返回已经 fulfilled 的 Promise 的结果，其他 Promise 被忽略
+func settledPromise(p *goja.Promise) (goja.Value, error) {
+	if p.State() == goja.PromiseStateFulfilled {
+		return p.Result(), nil
+	}
+	return nil, nil
+}
+
+// toNode 将 i 转为 Node，使用 await 得到其中的 Promise 的结果，await 返回的错误会被返回
+func toNode(i interface{}, await func(p *goja.Promise) (goja.Value, error)) (Node, error) {
+	if isNullish(i) {
+		return nil, nil
+	}
+	switch t := i.(type) {
+	case Node:
+		return t, nil
+	case bool:
+		return nil, nil
+	case string:
+		return &Text{Text: t}, nil
+	case *goja.Object:
+		if t.ClassName() == "Function" {
+			return nil, nil
+		}
+		if t.ClassName() == "Array" || isPromise(t) {
+			return toNode(t.Export(), await)
+		}
+	case *goja.Promise:
+		v, err := await(t)
+		if err != nil {
+			return nil, err
+		}
+		return toNode(v, await)
+	case goja.Value:
+		return toNode(t.Export(), await)
+	case Callable, func(goja.FunctionCall) goja.Value:
+		return nil, nil
+	}
+
+	var children []Node
+	var err error
+	if forEachItem(i, func(c interface{}) bool {
+		var n Node
+		n, err = toNode(c, await)
+		if n != nil {
+			children = append(children, n)
+		}
+		return err == nil
+	}) {
+		if err != nil {
+			return nil, err
+		}
+		return &Fragment{Children: children}, nil
+	}
+
+	n, ok := toVNode(i)
+	if !ok {
+		// for literal, e.g. 1, 2, 3
+		return &Text{Text: fmt.Sprintf("%v", i)}, nil
+	}
+	if n.isHTML {
+		return &RawHTML{HTML: n.html}, nil
+	}
+
+	c, err := toNode(n.children, await)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		if f, ok := c.(*Fragment); ok && !f.head && !f.cdata {
+			children = f.Children
+		} else {
+			children = []Node{c}
+		}
+	}
+	if n.nodeName == "" && n.island == nil {
+		return &Fragment{Children: children, head: n.head, cdata: n.cdata, caught: n.caught, boundary: n.boundary, fallback: n.fallback}, nil
+	}
+
+	attrs := make([]Attribute, len(n.attrs))
+	for i, a := range n.attrs {
+		attrs[i] = Attribute{Key: a.key, Value: a.value}
+	}
+	return &Element{
+		NodeName:   n.nodeName,
+		Attributes: attrs,
+		Children:   children,
+		Key:        n.key,
+		island:     n.island,
+	}, nil
+}
+
+// Node 将默认导出的 VDom 转为 Node，需要使用 WithAutoExecJsx 执行。
+func (m *ModuleExport) Node() (Node, error) {
+	v, ok := m.Default.(VDom)
+	if !ok {
+		return nil, fmt.Errorf("default export is not a vdom: %T, use WithAutoExecJsx to execute the component", m.Default)
+	}
+	return ToNode(v), nil
+}
+
+// Walk 按深度优先的顺序遍历节点，f 返回 false 时不再遍历该节点的子节点。
+func Walk(n Node, f func(n Node) bool) {
+	if n == nil || !f(n) {
+		return
+	}
+	for _, c := range nodeChildren(n) {
+		Walk(c, f)
+	}
+}
+
+// Transform 从叶子节点开始（先处理子节点，再处理节点本身）使用 f 替换节点，返回新的根节点。
+// f 返回 nil 时删除该节点，返回 *Fragment 可以将一个节点替换为多个节点。
+func Transform(n Node, f func(n Node) (Node, error)) (Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+	switch t := n.(type) {
+	case *Element:
+		children, err := transformChildren(t.Children, f)
+		if err != nil {
+			return nil, err
+		}
+		t.Children = children
+	case *Fragment:
+		children, err := transformChildren(t.Children, f)
+		if err != nil {
+			return nil, err
+		}
+		t.Children = children
+	}
+	return f(n)
+}
+
+func transformChildren(children []Node, f func(n Node) (Node, error)) ([]Node, error) {
+	r := children[:0]
+	for _, c := range children {
+		n, err := Transform(c, f)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			r = append(r, n)
+		}
+	}
+	return r, nil
+}
+
+func nodeChildren(n Node) []Node {
+	switch t := n.(type) {
+	case *Element:
+		return t.Children
+	case *Fragment:
+		return t.Children
+	}
+	return nil
+}
+
+// nodeVNode 将 Node 转为 vnode 用于渲染
+func nodeVNode(n Node) (vnode, bool) {
+	switch t := n.(type) {
+	case *Element:
+		attrs := make([]attribute, len(t.Attributes))
+		for i, a := range t.Attributes {
+			attrs[i] = attribute{key: a.Key, value: a.Value}
+		}
+		var children interface{} = t.Children
+		if len(t.Children) == 1 {
+			// 插件没有修改的子节点与没有插件时一样渲染
+			if l, ok := t.Children[0].(*lazyNode); ok {
+				children = l.v
+			}
+		}
+		return vnode{nodeName: t.NodeName, attrs: attrs, children: children, key: t.Key, island: t.island, processed: t.processed}, true
+	case *Fragment:
+		return vnode{children: t.Children, head: t.head, cdata: t.cdata, caught: t.caught, boundary: t.boundary, fallback: t.fallback}, true
+	case *RawHTML:
+		return vnode{html: t.HTML, isHTML: true}, true
+	}
+	return vnode{}, false
+}
@@ -0,0 +1,81 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNode(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ex, err := j.ExecCode([]byte(`
+export default () => <article>
+	<h1 id="title">Title</h1>
+	<img src="a.png" alt="a"/>
+	<p>see <a href="b/c">c</a> and <a href="https://example.com">example</a>{1}</p>
+	<h2>Sub</h2>
+	<>{[<img src="b.png"/>]}</>
+	<div dangerouslySetInnerHTML={{__html: "<i>raw</i>"}}></div>
+	{{__dangerousHTML: "<!-- comment -->"}}
+</article>`), WithAutoExecJsx(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ex.Node()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// collect headings
+	var headings []string
+	Walk(n, func(n Node) bool {
+		if e, ok := n.(*Element); ok && len(e.NodeName) == 2 && e.NodeName[0] == 'h' {
+			headings = append(headings, e.NodeName+":"+e.Children[0].(*Text).Text)
+			return false
+		}
+		return true
+	})
+	assert.Equal(t, []string{"h1:Title", "h2:Sub"}, headings)
+
+	n, err = Transform(n, func(n Node) (Node, error) {
+		e, ok := n.(*Element)
+		if !ok {
+			return n, nil
+		}
+		switch e.NodeName {
+		case "img":
+			e.SetAttr("loading", "lazy")
+		case "a":
+			if href, _ := e.Attr("href"); !strings.Contains(href.(string), "://") {
+				e.SetAttr("href", "/docs/"+href.(string))
+			}
+		case "h2":
+			// 删除节点
+			return nil, nil
+		}
+		return n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := Render(n)
+	assert.Equal(t, `<article><h1 id="title">Title</h1><img alt="a" src="a.png" loading="lazy"/>`+
+		`<p>see <a href="/docs/b/c">c</a> and <a href="https://example.com">example</a>1</p>`+
+		`<img src="b.png" loading="lazy"/><div><i>raw</i></div><!-- comment --></article>`, s)
+
+	// 构造 Node
+	s, _ = Render(&Fragment{Children: []Node{
+		&Element{NodeName: "p", Attributes: []Attribute{{Key: "className", Value: "a"}}, Children: []Node{&Text{Text: "<a>"}}},
+		&RawHTML{HTML: "<br>"},
+	}})
+	assert.Equal(t, `<p class="a">&lt;a&gt;</p><br>`, s)
+
+	_, err = (&ModuleExport{Default: Any{}}).Node()
+	assert.Error(t, err)
+}
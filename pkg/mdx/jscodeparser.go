@@ -7,6 +7,7 @@ import (
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 	"regexp"
+	"strings"
 )
 
 type jsCodeParser struct {
@@ -78,23 +79,32 @@ func (b *jsCodeParser) Continue(node ast.Node, reader text.Reader, pc parser.Con
 }
 
 var jsCodeKey = parser.NewContextKey()
+var jsCodeLinesKey = parser.NewContextKey()
 
 func (b *jsCodeParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
 	lines := node.Lines()
 	before := GetJsCode(pc)
+	sourceLines := GetJsCodeLines(pc)
 
 	var buf bytes.Buffer
 	if before != "" {
 		buf.WriteString(before)
+		if strings.HasSuffix(before, "\n") {
+			// ";" 是新的一行
+			sourceLines = append(sourceLines, -1)
+		}
 		buf.WriteString(";\n")
 	}
 
+	source := reader.Source()
 	for i := 0; i < lines.Len(); i++ {
 		segment := lines.At(i)
-		buf.Write(segment.Value(reader.Source()))
+		buf.Write(segment.Value(source))
+		sourceLines = append(sourceLines, bytes.Count(source[:segment.Start], []byte("\n")))
 	}
 
 	pc.Set(jsCodeKey, buf.String())
+	pc.Set(jsCodeLinesKey, sourceLines)
 
 	// remove self
 	node.Parent().RemoveChild(node.Parent(), node)
@@ -108,6 +118,15 @@ func (b *jsCodeParser) CanAcceptIndentedLine() bool {
 	return false
 }
 
+// GetJsCodeLines 返回 GetJsCode 中的每一行在源码中的行号（从 0 开始），-1 表示不是来自源码的行
+func GetJsCodeLines(pc parser.Context) []int {
+	v := pc.Get(jsCodeLinesKey)
+	if v == nil {
+		return nil
+	}
+	return v.([]int)
+}
+
 func GetJsCode(pc parser.Context) string {
 	v := pc.Get(jsCodeKey)
 	if v == nil {
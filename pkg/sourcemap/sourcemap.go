@@ -1,7 +1,9 @@
+// Package sourcemap 读写 source map v3，行与列都从 0 开始计数。
 package sourcemap
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"sort"
@@ -56,10 +58,10 @@ func (m *Map) decodeMappings() {
 	}
 
 	r := strings.NewReader(m.Mappings)
-	var generatedLine = 1
+	var generatedLine = 0
 	var generatedColumn = 0
 	var originalFile = 0
-	var originalLine = 1
+	var originalLine = 0
 	var originalColumn = 0
 	var originalName = 0
 	for r.Len() != 0 {
@@ -217,7 +219,7 @@ func (m *Map) EncodeMappings() {
 	m.Mappings = buf.String()
 }
 
-func (m *Map) WriteTo(w io.Writer) error {
+func (m *Map) WriteTo(w io.Writer) (int64, error) {
 	if m.Version == 0 {
 		m.Version = 3
 	}
@@ -230,6 +232,35 @@ func (m *Map) WriteTo(w io.Writer) error {
 	if m.Sources == nil {
 		m.Sources = make([]string, 0)
 	}
-	enc := json.NewEncoder(w)
-	return enc.Encode(m)
+	bs, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(bs)
+	return int64(n), err
+}
+
+// Inline 返回内联的 sourceMappingURL 注释，追加到代码末尾后 esbuild 与 goja 都会读取它
+func (m *Map) Inline() (string, error) {
+	var b bytes.Buffer
+	if _, err := m.WriteTo(&b); err != nil {
+		return "", err
+	}
+	return "//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// Source 返回生成代码中的位置对应的原始位置，即同一行中在 column 之前的最后一个 mapping
+func (m *Map) Source(line, column int) (*Mapping, bool) {
+	m.decodeMappings()
+	ms := m.decodedMappings
+	if !sort.IsSorted(m) {
+		sort.Sort(m)
+	}
+	i := sort.Search(len(ms), func(i int) bool {
+		return ms[i].GeneratedLine > line || (ms[i].GeneratedLine == line && ms[i].GeneratedColumn > column)
+	})
+	if i == 0 || ms[i-1].GeneratedLine != line || ms[i-1].OriginalFile == "" {
+		return nil, false
+	}
+	return ms[i-1], true
 }
@@ -2,8 +2,10 @@ package sourcemap
 
 import (
 	"bytes"
+	"encoding/base64"
 	sourcemap2 "github.com/go-sourcemap/sourcemap"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -61,7 +63,7 @@ hihi {a}
 
 	m.EncodeMappings()
 	sm := bytes.Buffer{}
-	err := m.WriteTo(&sm)
+	_, err := m.WriteTo(&sm)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -77,3 +79,36 @@ hihi {a}
 	assert.Equal(t, name, "")
 	assert.Equal(t, source, "a.md")
 }
+
+func TestSource(t *testing.T) {
+	m := Map{}
+	m.AddMapping(&Mapping{GeneratedLine: 0, GeneratedColumn: 0, OriginalFile: "a.mdx", OriginalLine: 3, OriginalColumn: 0})
+	m.AddMapping(&Mapping{GeneratedLine: 0, GeneratedColumn: 10, OriginalFile: "a.mdx", OriginalLine: 4, OriginalColumn: 2})
+	m.AddMapping(&Mapping{GeneratedLine: 2, GeneratedColumn: 4, OriginalFile: "a.mdx", OriginalLine: 8, OriginalColumn: 0})
+
+	inline, err := m.Inline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(inline, "//# sourceMappingURL=data:application/json;base64,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := d.Source(0, 12)
+	if assert.True(t, ok) {
+		assert.Equal(t, Mapping{GeneratedLine: 0, GeneratedColumn: 10, OriginalFile: "a.mdx", OriginalLine: 4, OriginalColumn: 2}, *s)
+	}
+	s, ok = d.Source(2, 4)
+	if assert.True(t, ok) {
+		assert.Equal(t, 8, s.OriginalLine)
+	}
+	_, ok = d.Source(1, 0)
+	assert.False(t, ok)
+	_, ok = d.Source(2, 3)
+	assert.False(t, ok)
+}
@@ -0,0 +1,94 @@
+package gojsx
+
+import (
+	"fmt"
+)
+
+// RenderPlugin 在渲染每个元素之前被调用，可以修改元素的属性，或者返回其他节点替换它：
+//   - 返回 nil 删除元素
+//   - 返回 &Element{Children: []Node{el}} 包裹元素
+//   - 返回 &Fragment{Children: []Node{el, sibling}} 插入兄弟节点
+//
+// 多个插件按顺序执行，后一个插件接收前一个插件返回的元素，如果返回的不是 *Element，则跳过剩下的插件。
+// 每个元素只会被处理一次，插件返回的新节点不会再被处理，但 el 的子节点会在渲染时被处理。
+// el.Children 中原本的子节点没有被转换为 *Element 等类型（Walk 不会遍历它们），可以保留、删除或者在前后添加节点。
+type RenderPlugin func(el *Element) (Node, error)
+
+type renderPluginsOption []RenderPlugin
+
+func (r renderPluginsOption) applyRenderOptions(options *renderOptions) {
+	options.Plugins = append(options.Plugins, r...)
+}
+
+func (r renderPluginsOption) applyRunOptions(options *execOptions) {
+	options.RenderOptions = append(options.RenderOptions, r)
+}
+
+// WithRenderPlugins 为本次渲染添加 RenderPlugin，在 Option.RenderPlugins 之后执行。
+func WithRenderPlugins(plugins ...RenderPlugin) interface {
+	OptionExec
+	OptionRender
+} {
+	return renderPluginsOption(plugins)
+}
+
+// applyPlugins 使用插件处理 n，返回需要渲染的节点
+func applyPlugins(ctx *RenderCtx, n *vnode) (Node, error) {
+	el := vnodeElement(n)
+	children := make(map[Node]bool, len(el.Children))
+	for _, c := range el.Children {
+		children[c] = true
+	}
+
+	var r Node = el
+	var err error
+	for _, p := range ctx.plugins {
+		r, err = p(el)
+		if err != nil {
+			return nil, fmt.Errorf("render plugin <%s>: %w", n.nodeName, err)
+		}
+		e, ok := r.(*Element)
+		if !ok {
+			break
+		}
+		el = e
+	}
+
+	// 标记插件返回的元素，el 原本的子节点除外
+	Walk(r, func(n Node) bool {
+		if children[n] {
+			return false
+		}
+		if e, ok := n.(*Element); ok {
+			e.processed = true
+		}
+		return true
+	})
+	return r, nil
+}
+
+// vnodeElement 将 vnode 转为 *Element 用于插件处理，子节点不会被转换（见 lazyNode），
+// 它们在渲染时与没有插件时一样直接渲染，Promise 与 <ErrorBoundary> 也在渲染时处理
+func vnodeElement(n *vnode) *Element {
+	attrs := make([]Attribute, len(n.attrs))
+	for i, a := range n.attrs {
+		attrs[i] = Attribute{Key: a.key, Value: a.value}
+	}
+	var children []Node
+	if !isNullish(n.children) {
+		children = []Node{&lazyNode{v: n.children}}
+	}
+	return &Element{
+		NodeName:   n.nodeName,
+		Attributes: attrs,
+		Children:   children,
+		Key:        n.key,
+	}
+}
+
+// lazyNode 是 RenderPlugin 收到的元素中还没有转换为 Node 的子节点（VDom 或者 goja.Value），渲染时直接渲染 v
+type lazyNode struct {
+	v interface{}
+}
+
+func (*lazyNode) node() {}
@@ -0,0 +1,150 @@
+package gojsx
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRenderPlugins(t *testing.T) {
+	j, err := NewJsx(Option{
+		RenderPlugins: []RenderPlugin{
+			// cdn
+			func(el *Element) (Node, error) {
+				if src, ok := el.Attr("src"); ok && strings.HasPrefix(src.(string), "/") {
+					el.SetAttr("src", "https://cdn.example.com"+src.(string))
+				}
+				return el, nil
+			},
+			// external links
+			func(el *Element) (Node, error) {
+				if href, ok := el.Attr("href"); ok && el.NodeName == "a" && strings.Contains(href.(string), "://") {
+					el.SetAttr("rel", "noopener")
+				}
+				return el, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+export default () => <div>
+<img src="/a.png"/>
+<p><a href="https://example.com">example</a><a href="/docs">docs</a></p>
+</div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div><img src="https://cdn.example.com/a.png"/><p><a href="https://example.com" rel="noopener">example</a><a href="/docs">docs</a></p></div>`, s)
+
+	// WithRenderPlugins 在 Option.RenderPlugins 之后执行
+	s, _, err = j.RenderCode([]byte(`export default () => <img src="/a.png" class="x"/>`), nil, WithRenderPlugins(func(el *Element) (Node, error) {
+		if c, ok := el.Attr("class"); ok {
+			el.SetAttr("class", "app-"+c.(string))
+		}
+		return el, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<img src="https://cdn.example.com/a.png" class="app-x"/>`, s)
+}
+
+func TestRenderPluginReplace(t *testing.T) {
+	plugin := func(el *Element) (Node, error) {
+		switch el.NodeName {
+		case "table":
+			// 包裹元素，新的 div 不会被再次处理
+			return &Element{NodeName: "div", Attributes: []Attribute{{Key: "class", Value: "scroll"}}, Children: []Node{el}}, nil
+		case "h2":
+			// 插入兄弟节点
+			return &Fragment{Children: []Node{el, &Element{NodeName: "hr"}}}, nil
+		case "script":
+			return nil, nil
+		}
+		return el, nil
+	}
+
+	n := &Element{NodeName: "main", Children: []Node{
+		&Element{NodeName: "h2", Children: []Node{&Text{Text: "Title"}}},
+		&Element{NodeName: "script", Children: []Node{&Text{Text: "alert(1)"}}},
+		&Element{NodeName: "table", Children: []Node{
+			&Element{NodeName: "tr", Children: []Node{
+				&Element{NodeName: "td", Children: []Node{
+					&Element{NodeName: "h2"},
+				}},
+			}},
+		}},
+	}}
+
+	s, _ := Render(n, WithRenderPlugins(plugin))
+	assert.Equal(t, `<main><h2>Title</h2><hr/><div class="scroll"><table><tr><td><h2></h2><hr/></td></tr></table></div></main>`, s)
+}
+
+func TestRenderPluginError(t *testing.T) {
+	errForbidden := errors.New("forbidden")
+	_, err := RenderTo(&strings.Builder{}, &Element{NodeName: "div", Children: []Node{&Element{NodeName: "iframe"}}}, WithRenderPlugins(func(el *Element) (Node, error) {
+		if el.NodeName == "iframe" {
+			return nil, errForbidden
+		}
+		return el, nil
+	}))
+	assert.True(t, errors.Is(err, errForbidden))
+	assert.Equal(t, "render plugin <iframe>: forbidden", err.Error())
+}
+
+func TestRenderPluginAsync(t *testing.T) {
+	j, err := NewJsx(Option{RenderPlugins: []RenderPlugin{func(el *Element) (Node, error) {
+		el.SetAttr("data-plugin", "1")
+		return el, nil
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+async function Title() { await null; return <h1>title</h1> }
+export default () => <div><Title/></div>
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div data-plugin="1"><h1 data-plugin="1">title</h1></div>`, s)
+
+	_, _, err = j.RenderCode([]byte(`
+async function Broken() { await null; throw new Error("load failed") }
+export default () => <div><Broken/></div>
+`), nil)
+	assert.ErrorContains(t, err, "Error: load failed")
+}
+
+func TestRenderPluginChildren(t *testing.T) {
+	j, err := NewJsx(Option{RenderPlugins: []RenderPlugin{func(el *Element) (Node, error) {
+		switch el.NodeName {
+		case "ul":
+			// 在原本的子节点之后添加节点
+			el.Children = append(el.Children, &Element{NodeName: "li", Children: []Node{&Text{Text: "more"}}})
+		case "li":
+			el.SetAttr("class", "item")
+		case "pre":
+			// 替换子节点
+			el.Children = []Node{&Text{Text: "hidden"}}
+		}
+		return el, nil
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+async function Item({name}) { await null; return <li>{name}</li> }
+export default () => <div><ul>{["a", "b"].map(name => <Item name={name}/>)}</ul><pre><b>code</b></pre><script>{"if (a < b) {}"}</script></div>
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<div><ul><li class="item">a</li><li class="item">b</li><li>more</li></ul><pre>hidden</pre><script>if (a < b) {}</script></div>`, s)
+}
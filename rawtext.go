@@ -0,0 +1,115 @@
+package gojsx
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// rawTextElements 的子节点是原始文本，浏览器不会解析其中的实体（如 &lt;），所以不能转义。
+var rawTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"xmp":      true,
+	"iframe":   true,
+	"noembed":  true,
+	"noframes": true,
+}
+
+// jsonScriptTypes 是内容为 json 的 script 类型
+var jsonScriptTypes = map[string]bool{
+	"application/json":    true,
+	"application/ld+json": true,
+	"importmap":           true,
+	"speculationrules":    true,
+}
+
+// rawTextEscaper 返回 raw text 元素中文本的转义方法，不是 raw text 元素时返回 nil
+func rawTextEscaper(n *vnode) func(string) string {
+	if !rawTextElements[n.nodeName] {
+		return nil
+	}
+	if n.nodeName == "script" {
+		if t, ok := n.attr("type"); ok {
+			if t, ok := t.(string); ok && jsonScriptTypes[strings.ToLower(strings.TrimSpace(t))] {
+				return escapeJSONScript
+			}
+		}
+	}
+	tag := n.nodeName
+	return func(s string) string {
+		return escapeRawText(tag, s)
+	}
+}
+
+// escapeRawText 原样输出文本，但会将 </tag 替换为 <\/tag、<!-- 替换为 <\!--，避免提前结束元素或者进入 html 注释状态。
+// 替换后的内容在 js 字符串与正则中是等价的。
+func escapeRawText(tag, s string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '<' {
+			continue
+		}
+		rest := s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "!--"):
+			b.WriteString(s[last : i+1])
+			b.WriteString(`\`)
+			last = i + 1
+		case len(rest) > len(tag) && rest[0] == '/' && strings.EqualFold(rest[1:len(tag)+1], tag):
+			b.WriteString(s[last : i+1])
+			b.WriteString(`\`)
+			last = i + 1
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// escapeJSONScript 转义 json 中的 <、>、& 以及 U+2028、U+2029，转义后依然是等价的 json，可以安全的放在 <script type="application/json"> 中。
+func escapeJSONScript(s string) string {
+	var b strings.Builder
+	last := 0
+	for i, r := range s {
+		var e string
+		switch r {
+		case '<':
+			e = `\u003c`
+		case '>':
+			e = `\u003e`
+		case '&':
+			e = `\u0026`
+		case '\u2028':
+			e = `\u2028`
+		case '\u2029':
+			e = `\u2029`
+		default:
+			continue
+		}
+		b.WriteString(s[last:i])
+		b.WriteString(e)
+		last = i + utf8.RuneLen(r)
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// JSONScript 将 v 序列化为 json，结果可以安全的放在 <script type="application/json"> 中，也可以作为 js 表达式放在 <script> 中。
+//
+//	data, _ := gojsx.JSONScript(props)
+//	`<script id="data" type="application/json">` + data + `</script>`
+func JSONScript(v interface{}) (string, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	// json.Marshal 已经转义了 <、>、& 与 U+2028、U+2029，这里再处理一次保证不依赖这个行为
+	return escapeJSONScript(string(bs)), nil
+}
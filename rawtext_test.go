@@ -0,0 +1,49 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRawText(t *testing.T) {
+	j, err := NewJsx(Option{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := j.RenderCode([]byte(`
+const code = 'if (a < b && c > d) { console.log("</script><script>alert(1)</script>", "<!--") }'
+const css = 'a > b::after { content: "</STYLE>" }'
+const data = {html: "</script><!--", line: "\u2028"}
+export default () => <div>
+	<script>{code}</script>
+	<style>{css}</style>
+	<script type="application/json">{JSON.stringify(data)}</script>
+	<p>{"<b>"}</p>
+</div>`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `<div>`+
+		`<script>if (a < b && c > d) { console.log("<\/script><script>alert(1)<\/script>", "<\!--") }</script>`+
+		`<style>a > b::after { content: "<\/STYLE>" }</style>`+
+		`<script type="application/json">{"html":"\u003c/script\u003e\u003c!--","line":"\u2028"}</script>`+
+		`<p>&lt;b&gt;</p>`+
+		`</div>`, s)
+}
+
+func TestEscapeRawText(t *testing.T) {
+	assert.Equal(t, `a<b`, escapeRawText("script", `a<b`))
+	assert.Equal(t, `<\/script><\/SCRIPT <\!-- <\/scripts`, escapeRawText("script", `</script></SCRIPT <!-- </scripts`))
+	assert.Equal(t, `<\/style> </script>`, escapeRawText("style", `</style> </script>`))
+}
+
+func TestJSONScript(t *testing.T) {
+	s, err := JSONScript(map[string]interface{}{"a": "</script>&<!--\u2028\u2029"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"a":"\u003c/script\u003e\u0026\u003c!--\u2028\u2029"}`, s)
+	assert.Equal(t, `"\u003c\u003e"`, escapeJSONScript(`"<>"`))
+}
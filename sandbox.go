@@ -0,0 +1,138 @@
+package gojsx
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Sandbox 限制 js 可以导入的模块，用于执行不受信任的代码。原生模块（RegisterModule、WithNativeModule）不受限制。
+type Sandbox struct {
+	// Root 是可以导入的文件所在的目录，路径的形式与 Option.Fs 中的相同（如 "templates"、"/srv/templates"），为空表示不限制。
+	// 使用 StdFileSystem 时会解析符号链接，指向 Root 之外的符号链接也会被禁止；其他 fs.FS 需要自己保证其中没有这样的符号链接
+	Root string
+	// Packages 是可以导入的 bare package（如 "lodash"、"@scope/pkg"），nil 表示不限制。
+	// 内置的 gojsx 与 react/jsx-runtime 总是可以导入
+	Packages []string
+	// DisableDataImports 禁止导入 .json 与 .txt 文件
+	DisableDataImports bool
+}
+
+// ModuleAccessError is returned when the import is denied by Option.Sandbox.
+type ModuleAccessError struct {
+	// Import 是 import 或者 require 的模块，如 "../../etc/passwd.json"
+	Import string
+	Reason string
+}
+
+func (e *ModuleAccessError) Error() string {
+	return fmt.Sprintf("can't import %q: %s", e.Import, e.Reason)
+}
+
+// builtinPackages 是 jsx-runtime 提供的模块，见 registryLoader
+var builtinPackages = map[string]bool{
+	"gojsx":             true,
+	"react/jsx-runtime": true,
+}
+
+// dataExtensions 是 DisableDataImports 禁止导入的文件
+var dataExtensions = map[string]bool{
+	".json": true,
+	".txt":  true,
+}
+
+// checkRequire 用于 require.RequireHook，resolved 为空表示 bare package
+func (s *Sandbox) checkRequire(specifier, resolved string) error {
+	if builtinPackages[specifier] {
+		return nil
+	}
+	if s.DisableDataImports && dataExtensions[path.Ext(specifier)] {
+		return &ModuleAccessError{Import: specifier, Reason: "json and text imports are disabled"}
+	}
+
+	if resolved == "" {
+		if s.Packages != nil && !s.allowPackage(packageName(specifier)) {
+			return &ModuleAccessError{Import: specifier, Reason: fmt.Sprintf("package %q is not allowed", packageName(specifier))}
+		}
+		return nil
+	}
+	if !s.contains(resolved) {
+		return &ModuleAccessError{Import: specifier, Reason: fmt.Sprintf("%q is outside of the root %q", resolved, s.Root)}
+	}
+	return nil
+}
+
+func (s *Sandbox) allowPackage(name string) bool {
+	for _, p := range s.Packages {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// contains 返回 p 是否在 Root 中，只比较路径，不解析符号链接
+func (s *Sandbox) contains(p string) bool {
+	if s.Root == "" {
+		return true
+	}
+	return within(s.Root, p)
+}
+
+// within 返回 p 是否在 root 目录中
+func within(root, p string) bool {
+	root = path.Clean(root)
+	p = path.Clean(p)
+	if root == "." {
+		return !path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+	}
+	return p == root || strings.HasPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
+
+// realPathFS 由可以解析符号链接的 fs.FS 实现（如 StdFileSystem），返回文件真实的绝对路径
+type realPathFS interface {
+	realPath(name string) (string, error)
+}
+
+// containsFile 与 contains 相同，但如果 fileSys 可以解析符号链接，还会检查 p 真实的路径是否在 Root 中
+func (s *Sandbox) containsFile(fileSys fs.FS, p string) bool {
+	if !s.contains(p) {
+		return false
+	}
+	rfs, ok := fileSys.(realPathFS)
+	if !ok || s.Root == "" {
+		return true
+	}
+	real, err := rfs.realPath(p)
+	if err != nil {
+		// 文件不存在，读取时同样会失败
+		return true
+	}
+	root, err := rfs.realPath(s.Root)
+	if err != nil {
+		return false
+	}
+	return within(root, real)
+}
+
+// checkLoad 用于 registryLoader 与 island，禁止读取 Root 之外的文件（如在上级目录中查找 node_modules，或者指向 Root 之外的符号链接）
+// 以及隐式导入的 .json 文件（如 require("./data")）
+func (s *Sandbox) checkLoad(fileSys fs.FS, p string) error {
+	if !s.containsFile(fileSys, p) {
+		return &ModuleAccessError{Import: p, Reason: fmt.Sprintf("%q is outside of the root %q", p, s.Root)}
+	}
+	if s.DisableDataImports && dataExtensions[path.Ext(p)] && path.Base(p) != "package.json" {
+		return &ModuleAccessError{Import: p, Reason: "json and text imports are disabled"}
+	}
+	return nil
+}
+
+// packageName 返回 bare package 的名字，如 "lodash/fp" => "lodash"，"@scope/pkg/x" => "@scope/pkg"
+func packageName(specifier string) string {
+	parts := strings.SplitN(specifier, "/", 3)
+	if strings.HasPrefix(specifier, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
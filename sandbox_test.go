@@ -0,0 +1,141 @@
+package gojsx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSandbox(t *testing.T) {
+	fsys := fstest.MapFS{
+		"secret.json":                       {Data: []byte(`{"password": "x"}`)},
+		"site/data.json":                    {Data: []byte(`{"title": "Home"}`)},
+		"site/node_modules/lodash/index.js": {Data: []byte(`exports.upper = (s) => s.toUpperCase()`)},
+		"site/node_modules/evil/index.js":   {Data: []byte(`exports.x = 1`)},
+		"node_modules/outside/index.js":     {Data: []byte(`exports.x = 1`)},
+		"site/Page.tsx": {Data: []byte(`
+import {upper} from "lodash"
+import {Head} from "gojsx"
+export default () => <p><Head><title>t</title></Head>{upper("ok")}</p>`)},
+		"site/Data.tsx":     {Data: []byte(`import data from "./data.json"; export default () => <p>{data.title}</p>`)},
+		"site/Implicit.tsx": {Data: []byte(`import data from "./data"; export default () => <p>{data.title}</p>`)},
+		"site/Secret.tsx":   {Data: []byte(`import s from "../secret.json"; export default () => <p>{s.password}</p>`)},
+		"site/Evil.tsx":     {Data: []byte(`import {x} from "evil"; export default () => <p>{x}</p>`)},
+		"site/evil.ts":      {Data: []byte(`import {x} from "evil"; export default x`)},
+		"site/Outside.tsx":  {Data: []byte(`import {x} from "outside"; export default () => <p>{x}</p>`)},
+	}
+
+	j, err := NewJsx(Option{Fs: fsys, Sandbox: &Sandbox{Root: "site", Packages: []string{"lodash", "outside"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := j.Render("./site/Page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>OK</p>", s)
+
+	s, err = j.Render("./site/Data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>Home</p>", s)
+
+	var ae *ModuleAccessError
+	_, err = j.Render("./site/Secret", nil)
+	if assert.ErrorAs(t, err, &ae) {
+		assert.Equal(t, "../secret.json", ae.Import)
+		assert.Contains(t, err.Error(), `can't import "../secret.json": "secret.json" is outside of the root "site"`)
+	}
+
+	_, err = j.Render("./site/Evil", nil)
+	if assert.ErrorAs(t, err, &ae) {
+		assert.Equal(t, `can't import "evil": package "evil" is not allowed`, ae.Error())
+	}
+
+	// 不会在 Root 之外查找 node_modules
+	_, err = j.Render("./site/Outside", nil)
+	if assert.ErrorAs(t, err, &ae) {
+		assert.Equal(t, "node_modules/outside/index.js", ae.Import)
+	}
+
+	_, err = j.Render("./secret.json", nil)
+	assert.ErrorAs(t, err, &ae)
+
+	// island 的依赖也会被限制
+	_, err = j.BuildIsland(Island{Src: "site/evil.ts", Export: "default"}, IslandBuildOptions{HydrateImport: `const h = 1, hydrate = 1;`})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `can't import "evil"`)
+	}
+
+	// 禁止导入 json
+	j, err = NewJsx(Option{Fs: fsys, Sandbox: &Sandbox{Root: "site", DisableDataImports: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = j.Render("./site/Data", nil)
+	if assert.ErrorAs(t, err, &ae) {
+		assert.Equal(t, "./data.json", ae.Import)
+	}
+	_, err = j.Render("./site/Implicit", nil)
+	if assert.ErrorAs(t, err, &ae) {
+		assert.Equal(t, `can't import "site/data.json": json and text imports are disabled`, ae.Error())
+	}
+}
+
+func TestSandboxSymlink(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"outside/secret.json": `{"password": "x"}`,
+		"site/lib/name.json":  `{"name": "lib"}`,
+		"site/Secret.tsx":     `import s from "./link/secret.json"; export default () => <p>{s.password}</p>`,
+		"site/Implicit.tsx":   `import s from "./link/secret"; export default () => <p>{s.password}</p>`,
+		"site/Inner.tsx":      `import s from "./inner/name.json"; export default () => <p>{s.name}</p>`,
+	}
+	for name, code := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// site/link -> ../outside，site/inner -> lib
+	if err := os.Symlink("../outside", filepath.Join(dir, "site/link")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink("lib", filepath.Join(dir, "site/inner")); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := NewJsx(Option{Sandbox: &Sandbox{Root: filepath.Join(dir, "site")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ae *ModuleAccessError
+	for _, name := range []string{"Secret", "Implicit"} {
+		_, err = j.Render(filepath.Join(dir, "site", name+".tsx"), nil)
+		if assert.ErrorAs(t, err, &ae, name) {
+			assert.Equal(t, filepath.Join(dir, "site/link/secret.json"), ae.Import)
+			assert.Contains(t, ae.Reason, "is outside of the root")
+		}
+	}
+
+	// 指向 Root 中的符号链接可以导入
+	s, err := j.Render(filepath.Join(dir, "site/Inner.tsx"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>lib</p>", s)
+}
+
+func TestPackageName(t *testing.T) {
+	assert.Equal(t, "lodash", packageName("lodash/fp"))
+	assert.Equal(t, "@scope/pkg", packageName("@scope/pkg/x"))
+	assert.Equal(t, "preact", packageName("preact"))
+}
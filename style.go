@@ -0,0 +1,139 @@
+package gojsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isUnitlessNumber 是数值不需要添加 px 单位的 css 属性
+//
+// /node_modules/react-dom/cjs/react-dom-server.node.development.js isUnitlessNumber
+var isUnitlessNumber = map[string]bool{
+	"animationIterationCount": true,
+	"aspectRatio":             true,
+	"borderImageOutset":       true,
+	"borderImageSlice":        true,
+	"borderImageWidth":        true,
+	"boxFlex":                 true,
+	"boxFlexGroup":            true,
+	"boxOrdinalGroup":         true,
+	"columnCount":             true,
+	"columns":                 true,
+	"flex":                    true,
+	"flexGrow":                true,
+	"flexPositive":            true,
+	"flexShrink":              true,
+	"flexNegative":            true,
+	"flexOrder":               true,
+	"gridArea":                true,
+	"gridRow":                 true,
+	"gridRowEnd":              true,
+	"gridRowSpan":             true,
+	"gridRowStart":            true,
+	"gridColumn":              true,
+	"gridColumnEnd":           true,
+	"gridColumnSpan":          true,
+	"gridColumnStart":         true,
+	"fontWeight":              true,
+	"lineClamp":               true,
+	"lineHeight":              true,
+	"opacity":                 true,
+	"order":                   true,
+	"orphans":                 true,
+	"tabSize":                 true,
+	"widows":                  true,
+	"zIndex":                  true,
+	"zoom":                    true,
+
+	// SVG-related properties
+	"fillOpacity":      true,
+	"floodOpacity":     true,
+	"stopOpacity":      true,
+	"strokeDasharray":  true,
+	"strokeDashoffset": true,
+	"strokeMiterlimit": true,
+	"strokeOpacity":    true,
+	"strokeWidth":      true,
+}
+
+// vendorPrefixes 与 react 一样，带浏览器前缀的属性（如 WebkitLineClamp）同样不需要单位
+var vendorPrefixes = []string{"Webkit", "ms", "Moz", "O"}
+
+// unitlessStyle 判断 css 属性的数值是否不需要单位
+func unitlessStyle(name string) bool {
+	if isUnitlessNumber[name] {
+		return true
+	}
+	for _, p := range vendorPrefixes {
+		if len(name) > len(p) && strings.HasPrefix(name, p) {
+			rest := name[len(p):]
+			if rest[0] >= 'A' && rest[0] <= 'Z' && isUnitlessNumber[strings.ToLower(rest[:1])+rest[1:]] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isCustomProperty 判断是否为 css 变量，如 --main-color，它的名字和值都原样输出
+func isCustomProperty(name string) bool {
+	return strings.HasPrefix(name, "--")
+}
+
+// styleName 返回 css 属性名
+func styleName(name string) string {
+	if isCustomProperty(name) {
+		return name
+	}
+	return hyphenateStyleName(name)
+}
+
+// styleValue 与 react 的 dangerousStyleValue 一致：null、undefined、bool 与空字符串会被忽略（返回 false），
+// 非 0 的数值会添加 px 单位（unitless 属性和 css 变量除外）。
+func styleValue(name string, v interface{}) (string, bool) {
+	var n float64
+	switch t := v.(type) {
+	case nil, bool:
+		return "", false
+	case string:
+		t = strings.TrimSpace(t)
+		return t, t != ""
+	case int:
+		n = float64(t)
+	case int8:
+		n = float64(t)
+	case int16:
+		n = float64(t)
+	case int32:
+		n = float64(t)
+	case int64:
+		n = float64(t)
+	case uint:
+		n = float64(t)
+	case uint8:
+		n = float64(t)
+	case uint16:
+		n = float64(t)
+	case uint32:
+		n = float64(t)
+	case uint64:
+		n = float64(t)
+	case float32:
+		n = float64(t)
+	case float64:
+		n = t
+	default:
+		if isNullish(v) {
+			return "", false
+		}
+		s := strings.TrimSpace(fmt.Sprintf("%v", v))
+		return s, s != ""
+	}
+
+	s := strconv.FormatFloat(n, 'f', -1, 64)
+	if n != 0 && !isCustomProperty(name) && !unitlessStyle(name) {
+		s += "px"
+	}
+	return s, true
+}
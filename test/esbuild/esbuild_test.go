@@ -7,7 +7,7 @@ import (
 	"github.com/evanw/esbuild/pkg/api"
 	sourcemap2 "github.com/go-sourcemap/sourcemap"
 	"github.com/zbysir/gojsx"
-	"github.com/zbysir/gojsx/test/sourcemap"
+	"github.com/zbysir/gojsx/pkg/sourcemap"
 	"strings"
 	"testing"
 )
@@ -64,7 +64,7 @@ hihi {a}
 
 	m.EncodeMappings()
 	sm := bytes.Buffer{}
-	err := m.WriteTo(&sm)
+	_, err := m.WriteTo(&sm)
 	if err != nil {
 	}
 
@@ -2,39 +2,132 @@ package gojsx
 
 import (
 	"context"
+	"fmt"
 	pool "github.com/jolestar/go-commons-pool/v2"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// poolConfig 见 Option 中 Vm 开头的字段
+type poolConfig struct {
+	maxTotal int
+	// maxIdle 为负数表示不限制
+	maxIdle          int
+	minIdle          int
+	prewarm          int
+	maxIdleTime      time.Duration
+	evictionInterval time.Duration
+	borrowTimeout    time.Duration
+}
+
+// defaultEvictionInterval 是指定了 VmMaxIdleTime 或 VmMinIdle 但没有指定 VmEvictionInterval 时检查空闲对象的间隔
+const defaultEvictionInterval = time.Minute
+
 type tPool[T any] struct {
-	op *pool.ObjectPool
+	op     *pool.ObjectPool
+	config poolConfig
+
+	created int64
+
+	waitLock sync.Mutex
+	borrows  int64
+	waitTime time.Duration
+	maxWait  time.Duration
 }
 
-func newTPool[T any](maxTotal int, fun func() T) *tPool[T] {
+func newTPool[T any](config poolConfig, fun func() T) *tPool[T] {
+	p := &tPool[T]{config: config}
 	factory := pool.NewPooledObjectFactorySimple(
 		func(context.Context) (interface{}, error) {
+			atomic.AddInt64(&p.created, 1)
 			return fun(), nil
 		})
+
+	interval := config.evictionInterval
+	if interval <= 0 && (config.maxIdleTime > 0 || config.minIdle > 0) {
+		interval = defaultEvictionInterval
+	}
+	// MinIdle 不能大于 MaxIdle，所以不使用 -1 表示不限制
+	maxIdle := config.maxIdle
+	if maxIdle < 0 {
+		maxIdle = config.maxTotal
+	}
+	idleTime := config.maxIdleTime
+	if idleTime <= 0 {
+		idleTime = math.MaxInt64
+	}
+
 	ctx := context.Background()
-	p := pool.NewObjectPool(ctx, factory, &pool.ObjectPoolConfig{
-		MaxIdle:            -1,
-		MaxTotal:           maxTotal,
+	p.op = pool.NewObjectPool(ctx, factory, &pool.ObjectPoolConfig{
+		// 总是使用最近使用过的对象，其他对象才会因为空闲被回收
+		LIFO:               true,
+		MaxIdle:            maxIdle,
+		MinIdle:            config.minIdle,
+		MaxTotal:           config.maxTotal,
 		BlockWhenExhausted: true,
+		// 空闲超过 maxIdleTime 的对象会被回收，但至少保留 minIdle 个
+		MinEvictableIdleTime:     math.MaxInt64,
+		SoftMinEvictableIdleTime: idleTime,
+		NumTestsPerEvictionRun:   -1,
+		EvictionPolicyName:       pool.DefaultEvictionPolicyName,
+		TimeBetweenEvictionRuns:  interval,
+		EvictionContext:          ctx,
 	})
 
-	return &tPool[T]{
-		op: p,
+	prewarm := config.prewarm
+	if config.maxTotal > 0 && prewarm > config.maxTotal {
+		prewarm = config.maxTotal
 	}
+	pool.Prefill(ctx, p.op, prewarm)
+	p.op.PreparePool(ctx)
+
+	return p
+}
+
+// PoolExhaustedError is returned when no vm is available in the pool within Option.VmBorrowTimeout.
+type PoolExhaustedError struct {
+	MaxTotal int
+	Timeout  time.Duration
+}
+
+func (e *PoolExhaustedError) Error() string {
+	return fmt.Sprintf("vm pool exhausted: no vm available in %v (max total %d)", e.Timeout, e.MaxTotal)
 }
 
-func (p *tPool[T]) Get() (t T, err error) {
-	o, err := p.op.BorrowObject(context.Background())
+// Get borrow an object, it stops waiting when ctx is done or borrowTimeout is exceeded.
+func (p *tPool[T]) Get(ctx context.Context) (t T, err error) {
+	bctx := ctx
+	if p.config.borrowTimeout > 0 {
+		var cancel context.CancelFunc
+		bctx, cancel = context.WithTimeout(ctx, p.config.borrowTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	o, err := p.op.BorrowObject(bctx)
+	p.addWait(time.Since(start))
 	if err != nil {
+		if bctx.Err() != nil && ctx.Err() == nil {
+			return t, &PoolExhaustedError{MaxTotal: p.config.maxTotal, Timeout: p.config.borrowTimeout}
+		}
 		return
 	}
 
 	return o.(T), nil
 }
 
+func (p *tPool[T]) addWait(d time.Duration) {
+	p.waitLock.Lock()
+	defer p.waitLock.Unlock()
+	p.borrows++
+	p.waitTime += d
+	if d > p.maxWait {
+		p.maxWait = d
+	}
+}
+
 func (p *tPool[T]) Put(t T) error {
 	err := p.op.ReturnObject(context.Background(), t)
 	if err != nil {
@@ -42,3 +135,41 @@ func (p *tPool[T]) Put(t T) error {
 	}
 	return nil
 }
+
+// Invalidate destroy a borrowed object, it will not be returned to the pool.
+func (p *tPool[T]) Invalidate(t T) error {
+	return p.op.InvalidateObject(context.Background(), t)
+}
+
+// Close 停止检查空闲对象的 goroutine 并销毁空闲的对象，借出的对象在归还时销毁
+func (p *tPool[T]) Close() {
+	p.op.Close(context.Background())
+}
+
+// PoolStats 是 vm 对象池的统计，见 Jsx.PoolStats
+type PoolStats struct {
+	// Active 是正在使用的 vm 数量，Idle 是空闲的 vm 数量
+	Active int
+	Idle   int
+	// Created 与 Destroyed 是创建与销毁（如空闲被回收、执行超时）的 vm 总数
+	Created   int64
+	Destroyed int64
+	// Borrows 是借出 vm 的次数，WaitTime 是借出 vm 的总等待时间，MaxWaitTime 是最长的一次
+	Borrows     int64
+	WaitTime    time.Duration
+	MaxWaitTime time.Duration
+}
+
+func (p *tPool[T]) Stats() PoolStats {
+	p.waitLock.Lock()
+	defer p.waitLock.Unlock()
+	return PoolStats{
+		Active:      p.op.GetNumActive(),
+		Idle:        p.op.GetNumIdle(),
+		Created:     atomic.LoadInt64(&p.created),
+		Destroyed:   int64(p.op.GetDestroyedCount()),
+		Borrows:     p.borrows,
+		WaitTime:    p.waitTime,
+		MaxWaitTime: p.maxWait,
+	}
+}
@@ -0,0 +1,90 @@
+package gojsx
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPoolPrewarmAndEviction(t *testing.T) {
+	j, err := NewJsx(Option{VmPrewarm: 3, VmMinIdle: 1, VmMaxIdleTime: 50 * time.Millisecond, VmEvictionInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	s := j.PoolStats()
+	assert.Equal(t, 3, s.Idle)
+	assert.Equal(t, int64(3), s.Created)
+
+	// 空闲的 vm 被回收，保留 VmMinIdle 个
+	assert.Eventually(t, func() bool {
+		return j.PoolStats().Idle == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), j.PoolStats().Destroyed)
+
+	_, err = j.ExecCode([]byte(`export default 1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = j.PoolStats()
+	assert.Equal(t, int64(1), s.Borrows)
+	assert.Equal(t, 0, s.Active)
+}
+
+func TestPoolBorrowTimeout(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 1, VmBorrowTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		_, _ = j.ExecCodeContext(ctx, []byte(`while (true) {}`))
+	}()
+	// 等待唯一的 vm 被借出
+	assert.Eventually(t, func() bool {
+		return j.PoolStats().Active == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = j.ExecCode([]byte(`export default 1`))
+	var pe *PoolExhaustedError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, 1, pe.MaxTotal)
+		assert.Equal(t, "vm pool exhausted: no vm available in 50ms (max total 1)", pe.Error())
+	}
+	s := j.PoolStats()
+	assert.Equal(t, int64(2), s.Borrows)
+	assert.GreaterOrEqual(t, s.MaxWaitTime, 50*time.Millisecond)
+	assert.GreaterOrEqual(t, s.WaitTime, s.MaxWaitTime)
+
+	// 调用者的 ctx 结束时依然返回 TimeoutError
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = j.ExecCodeContext(ctx, []byte(`export default 1`))
+	var te *TimeoutError
+	assert.ErrorAs(t, err, &te)
+
+	<-done
+	// 被中断的 vm 被销毁
+	assert.Equal(t, int64(1), j.PoolStats().Destroyed)
+	_, err = j.ExecCode([]byte(`export default 1`))
+	assert.NoError(t, err)
+}
+
+func TestPoolClose(t *testing.T) {
+	j, err := NewJsx(Option{VmPrewarm: 2, VmMaxIdleTime: time.Minute, VmEvictionInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, j.Close())
+	assert.Equal(t, 0, j.PoolStats().Idle)
+	assert.Equal(t, int64(2), j.PoolStats().Destroyed)
+
+	_, err = j.ExecCode([]byte(`export default 1`))
+	assert.Error(t, err)
+}
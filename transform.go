@@ -12,7 +12,9 @@ import (
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"github.com/zbysir/gojsx/pkg/mdx"
 	"go.abhg.dev/goldmark/mermaid"
 	"log"
@@ -71,9 +73,8 @@ func trapBOM(fileBytes []byte) []byte {
 	return trimmedBytes
 }
 
-// TODO SourceMap
-// 将 md 转换成 jsx 语法
-func (e *EsBuildTransform) transformMarkdown(ext string, src []byte) (out []byte, err error) {
+// 将 md 转换成 jsx 语法，生成的代码中内联了指向 md 源码的 sourcemap
+func (e *EsBuildTransform) transformMarkdown(file string, ext string, src []byte) (out []byte, err error) {
 	// 将 md 处理成 xhtml
 	var mdHtml bytes.Buffer
 	ctx := parser.NewContext()
@@ -107,6 +108,9 @@ func (e *EsBuildTransform) transformMarkdown(ext string, src []byte) (out []byte
 	}
 
 	opts = append(opts, e.markdownOptions...)
+	opts = append(opts, goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(lineMarkerRenderer{}, 0)),
+	))
 	md := goldmark.New(opts...)
 
 	doc := md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
@@ -115,7 +119,9 @@ func (e *EsBuildTransform) transformMarkdown(ext string, src []byte) (out []byte
 		doc.Dump(src, 1)
 	}
 
+	blocks := insertLineMarkers(doc, src)
 	err = md.Renderer().Render(&mdHtml, src, doc)
+	removeLineMarkers(doc)
 	if err != nil {
 		return
 	}
@@ -124,8 +130,9 @@ func (e *EsBuildTransform) transformMarkdown(ext string, src []byte) (out []byte
 	jsCode := mdx.GetJsCode(ctx)
 
 	var code bytes.Buffer
-	code.WriteString(jsCode)
-	code.WriteString(";\n")
+	sm := newMarkdownSourceMap(file, src)
+	sm.writeCode(&code, jsCode, mdx.GetJsCodeLines(ctx))
+	sm.write(&code, ";\n")
 
 	exportObj := map[string]interface{}{
 		"meta": toStrMap(m),
@@ -139,16 +146,24 @@ func (e *EsBuildTransform) transformMarkdown(ext string, src []byte) (out []byte
 	}
 
 	for k, v := range exportObj {
-		code.WriteString(fmt.Sprintf("export let %s = ", k))
 		bs, _ := json.Marshal(v)
-		code.Write(bs)
-		code.WriteString(";\n")
+		sm.write(&code, fmt.Sprintf("export let %s = %s;\n", k, bs))
 	}
 
 	// write jsx
-	code.WriteString("export default (props)=> <>")
-	mdHtml.WriteTo(&code)
-	code.WriteString("</>")
+	sm.write(&code, "export default (props)=> <>")
+	sm.writeHtml(&code, mdHtml.Bytes(), blocks)
+	sm.write(&code, "</>")
+
+	inline, err := sm.inline()
+	if err != nil {
+		return nil, err
+	}
+	if inline != "" {
+		code.WriteString("\n")
+		code.WriteString(inline)
+		code.WriteString("\n")
+	}
 
 	return code.Bytes(), nil
 }
@@ -207,7 +222,7 @@ func (e *EsBuildTransform) Transform(filePath string, code []byte, format Transf
 	var loader api.Loader
 	switch ext {
 	case ".md", ".mdx":
-		code, err = e.transformMarkdown(ext, code)
+		code, err = e.transformMarkdown(file, ext, code)
 		if err != nil {
 			return
 		}
@@ -299,7 +314,7 @@ func (e *EsBuildTransform) trySourcemapLocation(l *api.Location, source []byte)
 		return l
 	}
 
-	sourcemapJson, _ := base64.URLEncoding.DecodeString(string(sms[1]))
+	sourcemapJson, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sms[1])))
 	if sourcemapJson == nil {
 		return l
 	}
@@ -326,5 +341,9 @@ func (e *EsBuildTransform) trySourcemapLocation(l *api.Location, source []byte)
 }
 
 func sourceLine(s string, i int) string {
-	return strings.SplitN(s, "\n", i)[i-1]
+	lines := strings.Split(s, "\n")
+	if i < 1 || i > len(lines) {
+		return ""
+	}
+	return lines[i-1]
 }
@@ -0,0 +1,136 @@
+package gojsx
+
+import (
+	"context"
+	"github.com/dop251/goja"
+	"github.com/zbysir/gojsx/internal/pkg/goja_nodejs/require"
+)
+
+// execScope 是一次执行（如 ExecCode、Render）指定的全局变量与原生模块，它们只在这次执行中可见。
+// 执行导出的函数在执行结束之后被调用时（见 vmWithRegistry.callable），也会重新使用这次执行的 execScope。
+type execScope struct {
+	globals map[string]interface{}
+	modules map[string]require.ModuleLoader
+	// limits 同样限制执行结束之后调用的导出的函数
+	limits Limits
+}
+
+func newExecScope(p execOptions, limits Limits) *execScope {
+	s := &execScope{globals: p.GlobalVars, limits: limits}
+	for _, mod := range p.NativeModules {
+		if s.modules == nil {
+			s.modules = map[string]require.ModuleLoader{}
+		}
+		obj := mod.Obj
+		s.modules[mod.Path] = func(runtime *goja.Runtime, module *goja.Object) {
+			o := module.Get("exports").(*goja.Object)
+			for k, v := range obj {
+				_ = o.Set(k, moduleValue(runtime, k, v))
+			}
+		}
+	}
+	return s
+}
+
+// savedGlobal 是被 execScope 覆盖的全局变量原本的值，v 为 nil 表示原本不存在
+type savedGlobal struct {
+	k string
+	v goja.Value
+}
+
+// enter 设置 scope 中的全局变量与原生模块，需要持有 mu。
+func (v *vmWithRegistry) enter(s *execScope) error {
+	v.saved = v.saved[:0]
+	g := v.vm.GlobalObject()
+	for k, val := range s.globals {
+		v.saved = append(v.saved, savedGlobal{k: k, v: g.Get(k)})
+		if err := v.vm.Set(k, val); err != nil {
+			v.exit(s)
+			return err
+		}
+	}
+	v.requireModule.SetOverlay(s.modules)
+	return nil
+}
+
+// exit 恢复 enter 之前的全局变量并删除原生模块，之后 vm 才能被其他执行使用。
+func (v *vmWithRegistry) exit(s *execScope) {
+	g := v.vm.GlobalObject()
+	for i := len(v.saved) - 1; i >= 0; i-- {
+		if sv := v.saved[i]; sv.v == nil {
+			_ = g.Delete(sv.k)
+		} else {
+			_ = g.Set(sv.k, sv.v)
+		}
+	}
+	v.saved = v.saved[:0]
+
+	// 同时删除缓存中导入了本次执行的原生模块的模块
+	v.requireModule.SetOverlay(nil)
+}
+
+// lock 等待其他执行或者导出的函数结束之后占用 vm，ctx 结束时返回 *TimeoutError。
+// 使用 channel 而不是 sync.Mutex，等待可以被 ctx 取消
+func (v *vmWithRegistry) lock(ctx context.Context) error {
+	// 优先占用空闲的 vm，select 在多个 case 都满足时是随机的
+	select {
+	case v.busy <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case v.busy <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return &TimeoutError{Err: ctx.Err()}
+	}
+}
+
+func (v *vmWithRegistry) unlock() {
+	<-v.busy
+}
+
+// callable 返回在 scope 中调用 c 的 Callable，用于执行结束之后调用导出的函数，与执行一样受 scope.limits 限制。
+// 调用时会等待 vm 上正在进行的执行结束，所以不能在同一个 vm 的执行过程中调用。
+func (v *vmWithRegistry) callable(s *execScope, c goja.Callable) Callable {
+	return func(args ...interface{}) (r goja.Value, err error) {
+		parent := context.Background()
+		ctx, cancel := s.limits.limitContext(parent)
+		defer cancel()
+		defer func() {
+			err = s.limits.limitError(parent, asTimeoutError(ctx, err))
+		}()
+
+		if err = v.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer v.unlock()
+		stop := interruptOnDone(ctx, v.vm)
+		setCallStackSize(v.vm, s.limits.MaxCallStackSize)
+		defer func() {
+			stop()
+			setCallStackSize(v.vm, 0)
+			// vm 在对象池中，不能像执行那样被丢弃，清除可能在调用结束之后才发生的 Interrupt
+			v.vm.ClearInterrupt()
+		}()
+
+		if err = v.enter(s); err != nil {
+			return nil, err
+		}
+		defer v.exit(s)
+		return callFunction(v.vm, c, args...)
+	}
+}
+
+// callFunction 使用 args 调用 c，返回没有被 <ErrorBoundary> 捕获的组件错误
+func callFunction(vm *goja.Runtime, c goja.Callable, args ...interface{}) (goja.Value, error) {
+	as := make([]goja.Value, len(args))
+	for i, arg := range args {
+		as[i] = vm.ToValue(arg)
+	}
+	r, err := c(nil, as...)
+	if err != nil {
+		return nil, err
+	}
+	return r, pendingError(vm)
+}
@@ -0,0 +1,177 @@
+package gojsx
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestExecScope(t *testing.T) {
+	// 只有一个 vm，每次执行都使用同一个 vm
+	j, err := NewJsx(Option{VmMaxTotal: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RegisterModule("@test/env", map[string]interface{}{"name": "registry"})
+
+	code := []byte(`
+import {name} from "@test/env"
+export const getUser = () => typeof user === "undefined" ? "none" : user
+export default () => name + ":" + getUser()`)
+
+	first, err := j.ExecCode(code, WithGlobalVar("user", "a"), WithNativeModule("@test/env", map[string]interface{}{"name": "first"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := j.ExecCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(ex *ModuleExport) string {
+		v, err := ex.Default.(Callable)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.String()
+	}
+	// 全局变量与原生模块在执行结束之后被删除
+	assert.Equal(t, "registry:none", call(second))
+	// 之后调用导出的函数依然使用本次执行的值
+	assert.Equal(t, "first:a", call(first))
+	v, err := first.Exports["getUser"].(Callable)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "a", v.String())
+	assert.Equal(t, "registry:none", call(second))
+
+	// 异步的回调
+	s, _, err := j.RenderCode([]byte(`
+const User = async () => { await null; return <p>{user}</p> }
+export default () => <User/>`), nil, WithGlobalVar("user", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<p>b</p>", s)
+
+	// 被覆盖的全局变量会被恢复
+	_, err = j.ExecCode([]byte(`export default JSON.stringify(1)`), WithGlobalVar("JSON", map[string]interface{}{}))
+	assert.Error(t, err)
+	ex, err := j.ExecCode([]byte(`export default JSON.stringify(1)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Any{"1"}, ex.Default)
+}
+
+func TestExecScopeConcurrent(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := []byte(`export default () => user`)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ex, err := j.ExecCode(code, WithGlobalVar("user", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// 其他 goroutine 可能正在使用同一个 vm
+			v, err := ex.Default.(Callable)()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			assert.Equal(t, int64(i), v.ToInteger())
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestExecScopeModuleCache(t *testing.T) {
+	fileSys := fstest.MapFS{
+		// loads 记录模块被加载的次数
+		"lib.js":  {Data: []byte(`globalThis.loads = (globalThis.loads || 0) + 1; export const lib = "lib"`)},
+		"user.js": {Data: []byte(`import {name} from "@test/env"; export const user = name`)},
+	}
+	j, err := NewJsx(Option{VmMaxTotal: 1, Fs: fileSys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RegisterModule("@test/env", map[string]interface{}{"name": "registry"})
+
+	code := []byte(`
+import {lib} from "./lib"
+import {user} from "./user"
+export default lib + ":" + user + ":" + globalThis.loads`)
+	for _, name := range []string{"a", "b"} {
+		ex, err := j.ExecCode(code, WithCache(true), WithNativeModule("@test/env", map[string]interface{}{"name": name}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 没有使用原生模块的模块不会被重新加载，使用了的模块会重新加载
+		assert.Equal(t, Any{"lib:" + name + ":1"}, ex.Default)
+	}
+	ex, err := j.ExecCode(code, WithCache(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Any{"lib:registry:1"}, ex.Default)
+}
+
+func TestExecScopeRunawayExport(t *testing.T) {
+	j, err := NewJsx(Option{VmMaxTotal: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	var stop int32
+	started := make(chan struct{})
+	var once sync.Once
+	ex, err := j.ExecCode([]byte(`import {stopped} from "@test/loop"; export function loop() { while (!stopped()) {} }; export default 1`), WithNativeModule("@test/loop", map[string]interface{}{
+		"stopped": func() bool {
+			once.Do(func() { close(started) })
+			return atomic.LoadInt32(&stop) == 1
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = ex.Exports["loop"].(Callable)()
+	}()
+	defer func() {
+		atomic.StoreInt32(&stop, 1)
+		<-done
+	}()
+	<-started
+
+	// 导出的函数一直占用唯一的 vm，之后的执行在 ctx 结束时返回，而不是一直等待
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = j.ExecCodeContext(ctx, []byte(`export default 1`))
+	var te *TimeoutError
+	assert.ErrorAs(t, err, &te)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// 被占用的 vm 被丢弃，之后的执行使用新的 vm
+	ex, err = j.ExecCode([]byte(`export default 2`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Any{int64(2)}, ex.Default)
+}
@@ -0,0 +1,243 @@
+package gojsx
+
+import (
+	"github.com/dop251/goja"
+	"strconv"
+)
+
+// attribute 是元素的一个属性，渲染时保持 attributes 中的顺序
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// vnode 是 VDom 节点的统一表示，它可能读取自 Export 出来的 map，也可能直接读取自 goja.Object（不需要 Export 整个树）。
+type vnode struct {
+	nodeName string
+	// attrs 不包含 children
+	attrs    []attribute
+	children interface{}
+	key      interface{}
+
+	// for {{ __dangerousHTML: html }}
+	html   string
+	isHTML bool
+	// for <Head>
+	head bool
+	// for <CDATA>
+	cdata bool
+	// for <Counter client:load/>
+	island map[string]interface{}
+	// processed 为 true 时表示已经被 RenderPlugin 处理过
+	processed bool
+	// caught 是 <ErrorBoundary> 捕获的错误，包含 stack 与 componentStack
+	caught map[string]interface{}
+	// boundary 表示没有捕获到错误的 <ErrorBoundary>，子节点中的 Promise reject 时渲染 fallback，见 renderBoundary
+	boundary bool
+	fallback interface{}
+}
+
+// attr 返回属性值
+func (n *vnode) attr(k string) (interface{}, bool) {
+	for _, a := range n.attrs {
+		if a.key == k {
+			return a.value, true
+		}
+	}
+	return nil, false
+}
+
+// toVNode 将 map 或者 goja.Object 转为 vnode，其他类型返回 false
+func toVNode(c interface{}) (vnode, bool) {
+	switch t := c.(type) {
+	case map[string]interface{}:
+		return mapVNode(t), true
+	case VDom:
+		return mapVNode(t), true
+	case *goja.Object:
+		if t.ClassName() == "Object" && !isPromise(t) {
+			return gojaVNode(t, nil), true
+		}
+	case Node:
+		return nodeVNode(t)
+	}
+	return vnode{}, false
+}
+
+func mapVNode(m map[string]interface{}) vnode {
+	var n vnode
+	n.nodeName, _ = m["nodeName"].(string)
+	n.key = m["key"]
+	n.html, n.isHTML = m["__dangerousHTML"].(string)
+	n.head = m["__head"] == true
+	n.cdata = m["__cdata"] == true
+	n.island, _ = m["__island"].(map[string]interface{})
+	n.caught, _ = m["__caught"].(map[string]interface{})
+	if b, ok := m["__boundary"].(map[string]interface{}); ok {
+		n.boundary, n.fallback = true, b["fallback"]
+	}
+
+	if attrs, ok := m["attributes"].(map[string]interface{}); ok {
+		n.attrs = make([]attribute, 0, len(attrs))
+		// map 没有顺序，排序保证输出稳定
+		sortMap(attrs, func(k string, v interface{}) {
+			if k == "children" {
+				n.children = v
+				return
+			}
+			n.attrs = append(n.attrs, attribute{key: k, value: v})
+		})
+	}
+	return n
+}
+
+// mapAttributes 将 map 转为按 key 排序的属性列表
+func mapAttributes(m map[string]interface{}) []attribute {
+	attrs := make([]attribute, 0, len(m))
+	sortMap(m, func(k string, v interface{}) {
+		attrs = append(attrs, attribute{key: k, value: v})
+	})
+	return attrs
+}
+
+// gojaVNode 读取 goja.Object 中的节点，ctx 不为 nil 时从 ctx 中分配属性列表
+func gojaVNode(o *goja.Object, ctx *RenderCtx) vnode {
+	var n vnode
+	// 只读取需要的字段，避免遍历所有 key
+	n.nodeName, _ = gojaString(o.Get("nodeName"))
+	n.key = gojaExport(o.Get("key"))
+	if html := o.Get("__dangerousHTML"); html != nil {
+		n.html, n.isHTML = html.Export().(string)
+	}
+	if head := o.Get("__head"); head != nil {
+		n.head = head.ToBoolean()
+	}
+	if cdata := o.Get("__cdata"); cdata != nil {
+		n.cdata = cdata.ToBoolean()
+	}
+	if island := o.Get("__island"); island != nil {
+		n.island, _ = island.Export().(map[string]interface{})
+	}
+	if caught := o.Get("__caught"); caught != nil {
+		n.caught, _ = caught.Export().(map[string]interface{})
+	}
+	if b, ok := o.Get("__boundary").(*goja.Object); ok {
+		n.boundary, n.fallback = true, b.Get("fallback")
+	}
+
+	attrs, ok := o.Get("attributes").(*goja.Object)
+	if !ok {
+		return n
+	}
+	keys := attrs.Keys()
+	if ctx != nil {
+		n.attrs = ctx.allocAttrs(len(keys))
+	} else {
+		n.attrs = make([]attribute, 0, len(keys))
+	}
+	for _, k := range keys {
+		v := attrs.Get(k)
+		if k == "children" {
+			n.children = v
+			continue
+		}
+		n.attrs = append(n.attrs, attribute{key: k, value: gojaAttributeValue(k, v)})
+	}
+	return n
+}
+
+// attrsChunk 是 RenderCtx.allocAttrs 每次分配的属性数量
+const attrsChunk = 256
+
+// allocAttrs 返回长度为 0、容量为 n 的属性列表。它们从一块共享的内存中分配，而不是为每个元素单独分配，
+// 分配出去的列表不会被复用，所以可以被 RenderPlugin 等持有。
+func (ctx *RenderCtx) allocAttrs(n int) []attribute {
+	if n > cap(ctx.attrs)-len(ctx.attrs) {
+		size := attrsChunk
+		if n > size {
+			size = n
+		}
+		ctx.attrs = make([]attribute, 0, size)
+	}
+	l := len(ctx.attrs)
+	ctx.attrs = ctx.attrs[:l+n]
+	return ctx.attrs[l : l : l+n]
+}
+
+// gojaString 返回 js 字符串的值，不需要 Export 为 interface{}（每次 Export 都会分配内存）
+func gojaString(v goja.Value) (string, bool) {
+	if str, ok := v.(goja.String); ok {
+		return str.String(), true
+	}
+	return "", false
+}
+
+func gojaExport(v goja.Value) interface{} {
+	if v == nil {
+		return nil
+	}
+	return v.Export()
+}
+
+// gojaAttributeValue 将属性值转为 Go 类型。
+// 函数保留为 goja.Object，用于获取 hydrate 代码；style 保留为 goja.Object，用于保持顺序。
+func gojaAttributeValue(k string, v goja.Value) interface{} {
+	if o, ok := v.(*goja.Object); ok {
+		if o.ClassName() == "Function" || k == "style" {
+			return o
+		}
+	}
+	if v == nil {
+		return nil
+	}
+	return v.Export()
+}
+
+// forEachItem 遍历 []interface{}、[]Node 或 js 数组，c 不是数组时返回 false
+func forEachItem(c interface{}, f func(i interface{}) bool) bool {
+	switch t := c.(type) {
+	case []interface{}:
+		for _, i := range t {
+			if !f(i) {
+				break
+			}
+		}
+		return true
+	case []Node:
+		for _, i := range t {
+			if !f(i) {
+				break
+			}
+		}
+		return true
+	case *goja.Object:
+		if t.ClassName() != "Array" {
+			return false
+		}
+		l := int(t.Get("length").ToInteger())
+		for i := 0; i < l; i++ {
+			if !f(t.Get(strconv.Itoa(i))) {
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isPromise 判断 o 是否为 Promise，goja 中 Promise 的 ClassName 也是 "Object"
+func isPromise(o *goja.Object) bool {
+	tag := o.GetSymbol(goja.SymToStringTag)
+	return tag != nil && tag.String() == "Promise"
+}
+
+// isNullish 判断 c 是否为 nil、null 或 undefined
+func isNullish(c interface{}) bool {
+	switch t := c.(type) {
+	case nil:
+		return true
+	case goja.Value:
+		return goja.IsUndefined(t) || goja.IsNull(t)
+	}
+	return false
+}